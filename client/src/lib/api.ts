@@ -14,13 +14,17 @@ export interface Session {
 
 export interface UploadResponse {
   sessionId: string;
+  ownerToken: string; // Full access once collaborators are invited
   fields: string[];
   message: string;
 }
 
 export interface QuestionResponse {
   field: string;
-  fieldType: string; // "text", "number", or "date"
+  fieldType: string; // "text", "number", "date", "currency", "percent", "email", "phone", "address", "boolean", "select", ...
+  typeReason?: string; // Why the field has this type
+  options?: string[]; // Choices for select, boolean and entity type fields
+  subFields?: { name: string; label: string; required: boolean }[]; // Parts of an address
   question: string;
   isAIPhrased: boolean;
   progress: number;
@@ -43,33 +47,90 @@ export interface ErrorResponse {
   message?: string;
 }
 
+// Job is a background upload or generation, polled until it finishes
+export interface Job<T = unknown> {
+  id: string;
+  kind: string; // "upload", "package", "reverse" or "generate"
+  sessionId?: string;
+  status: 'queued' | 'running' | 'succeeded' | 'failed';
+  progress: number; // Percent done
+  stage?: string;
+  result?: T; // What the synchronous endpoint would have returned
+  error?: ErrorResponse;
+  downloadUrl?: string; // Set when the result is a file
+  finishedAt?: string;
+}
+
+const JOB_POLL_INTERVAL_MS = 500;
+
+// jobError turns a failed request's or job's error body into an Error
+function jobError(error: ErrorResponse | undefined, fallback: string): Error {
+  // Check if this is a Gemini quota exhaustion error
+  if (error?.error === 'gemini_quota_exhausted') {
+    return new Error(error.message || 'Gemini free tier quota has been exhausted. Please try again in 2-3 minutes.');
+  }
+  return new Error(error?.message || fallback);
+}
+
+// waitForJob polls a job until it finishes, throwing if it failed
+async function waitForJob<T>(jobId: string, fallback: string): Promise<Job<T>> {
+  for (;;) {
+    const response = await fetch(`${API_BASE_URL}/jobs/${jobId}`);
+    if (!response.ok) {
+      const error: ErrorResponse = await response.json();
+      throw jobError(error, fallback);
+    }
+    const job: Job<T> = await response.json();
+    if (job.finishedAt) {
+      if (job.status === 'failed') {
+        throw jobError(job.error, fallback);
+      }
+      return job;
+    }
+    await new Promise((resolve) => setTimeout(resolve, JOB_POLL_INTERVAL_MS));
+  }
+}
+
+// sessionHeaders sends the session's access token: from a collaborator link
+// (?token=) or the owner token saved at upload
+function sessionHeaders(sessionId: string): Record<string, string> {
+  const fromLink = new URLSearchParams(window.location.search).get('token');
+  if (fromLink) {
+    sessionStorage.setItem(`token:${sessionId}`, fromLink);
+  }
+  const token = sessionStorage.getItem(`token:${sessionId}`);
+  return token ? { 'X-Session-Token': token } : {};
+}
+
 // API functions
 export const api = {
-  // Upload document
+  // Upload document; field detection runs as a background job
   async uploadDocument(file: File): Promise<UploadResponse> {
     const formData = new FormData();
     formData.append('document', file);
 
-    const response = await fetch(`${API_BASE_URL}/upload`, {
+    const response = await fetch(`${API_BASE_URL}/upload?async=true`, {
       method: 'POST',
       body: formData,
     });
 
     if (!response.ok) {
       const error: ErrorResponse = await response.json();
-      // Check if this is a Gemini quota exhaustion error
-      if (error.error === 'gemini_quota_exhausted') {
-        throw new Error(error.message || 'Gemini free tier quota has been exhausted. Please try again in 2-3 minutes.');
-      }
-      throw new Error(error.message || 'Upload failed');
+      throw jobError(error, 'Upload failed');
     }
 
-    return response.json();
+    const queued: Job = await response.json();
+    const job = await waitForJob<UploadResponse>(queued.id, 'Upload failed');
+    const upload = job.result as UploadResponse;
+    sessionStorage.setItem(`token:${upload.sessionId}`, upload.ownerToken);
+    return upload;
   },
 
   // Get session status
   async getSession(sessionId: string): Promise<SessionStatusResponse> {
-    const response = await fetch(`${API_BASE_URL}/session/${sessionId}`);
+    const response = await fetch(`${API_BASE_URL}/session/${sessionId}`, {
+      headers: sessionHeaders(sessionId),
+    });
 
     if (!response.ok) {
       const error: ErrorResponse = await response.json();
@@ -81,7 +142,9 @@ export const api = {
 
   // Get next question
   async getNextQuestion(sessionId: string): Promise<QuestionResponse> {
-    const response = await fetch(`${API_BASE_URL}/session/${sessionId}/next`);
+    const response = await fetch(`${API_BASE_URL}/session/${sessionId}/next`, {
+      headers: sessionHeaders(sessionId),
+    });
 
     if (!response.ok) {
       const error: ErrorResponse = await response.json();
@@ -97,6 +160,7 @@ export const api = {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        ...sessionHeaders(sessionId),
       },
       body: JSON.stringify({ field, answer }),
     });
@@ -111,6 +175,7 @@ export const api = {
   async generateAIQuestions(sessionId: string): Promise<void> {
     const response = await fetch(`${API_BASE_URL}/session/${sessionId}/ai/questions`, {
       method: 'POST',
+      headers: sessionHeaders(sessionId),
     });
 
     if (!response.ok) {
@@ -119,10 +184,11 @@ export const api = {
     }
   },
 
-  // Download filled document
+  // Download filled document; generation runs as a background job
   async downloadDocument(sessionId: string): Promise<Blob> {
-    const response = await fetch(`${API_BASE_URL}/session/${sessionId}/generate`, {
+    const response = await fetch(`${API_BASE_URL}/session/${sessionId}/generate?async=true`, {
       method: 'POST',
+      headers: sessionHeaders(sessionId),
     });
 
     if (!response.ok) {
@@ -130,6 +196,14 @@ export const api = {
       throw new Error(error.message || 'Failed to generate document');
     }
 
-    return response.blob();
+    const queued: Job = await response.json();
+    await waitForJob(queued.id, 'Failed to generate document');
+    const download = await fetch(`${API_BASE_URL}/jobs/${queued.id}/download`);
+    if (!download.ok) {
+      const error: ErrorResponse = await download.json();
+      throw new Error(error.message || 'Failed to download document');
+    }
+
+    return download.blob();
   },
 };
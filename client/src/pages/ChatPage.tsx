@@ -108,7 +108,43 @@ export default function ChatPage() {
 
           <form onSubmit={handleSubmit}>
             <div className="mb-6">
-              {question.fieldType === 'date' ? (
+              {question.options && question.options.length > 0 ? (
+                <select
+                  value={answer}
+                  onChange={(e) => setAnswer(e.target.value)}
+                  className="w-full bg-gray-800 text-white border border-gray-700 rounded-xl px-4 py-3 focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent"
+                  disabled={submitting}
+                  autoFocus
+                >
+                  <option value="">Choose one...</option>
+                  {question.options.map((option) => (
+                    <option key={option} value={option}>
+                      {option}
+                    </option>
+                  ))}
+                </select>
+              ) : question.fieldType === 'email' || question.fieldType === 'phone' ? (
+                <input
+                  type={question.fieldType === 'email' ? 'email' : 'tel'}
+                  value={answer}
+                  onChange={(e) => setAnswer(e.target.value)}
+                  placeholder={question.fieldType === 'email' ? 'name@example.com' : '(555) 123-4567'}
+                  className="w-full bg-gray-800 text-white border border-gray-700 rounded-xl px-4 py-3 focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent"
+                  disabled={submitting}
+                  autoFocus
+                />
+              ) : question.fieldType === 'currency' || question.fieldType === 'percent' ? (
+                <input
+                  type="text"
+                  inputMode="decimal"
+                  value={answer}
+                  onChange={(e) => setAnswer(e.target.value)}
+                  placeholder={question.fieldType === 'currency' ? 'e.g. $500,000' : 'e.g. 20%'}
+                  className="w-full bg-gray-800 text-white border border-gray-700 rounded-xl px-4 py-3 focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent"
+                  disabled={submitting}
+                  autoFocus
+                />
+              ) : question.fieldType === 'date' ? (
                 <input
                   type="date"
                   value={answer}
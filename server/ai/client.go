@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider identifies the backend that serves chat completions
+type Provider string
+
+const (
+	ProviderNone   Provider = "none"
+	ProviderGemini Provider = "gemini"
+	ProviderOpenAI Provider = "openai"
+	ProviderLocal  Provider = "local" // Self-hosted OpenAI-compatible endpoint
+)
+
+var (
+	// ErrNoProvider is returned when no AI provider is configured
+	ErrNoProvider = errors.New("no AI provider configured")
+
+	// ErrQuotaExhausted is returned when the provider reports quota or rate limit exhaustion
+	ErrQuotaExhausted = errors.New("gemini_quota_exhausted")
+)
+
+// Request is a single chat completion request
+type Request struct {
+	System string // Instructions sent as the system message
+	Prompt string // User message
+}
+
+// Usage holds token counts reported by the provider
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// Response is the result of a chat completion
+type Response struct {
+	Text     string
+	Provider Provider
+	Model    string
+	Usage    Usage
+}
+
+// Client sends chat completions to a configured provider
+type Client interface {
+	Provider() Provider
+	Model() string
+	Complete(req Request) (*Response, error)
+}
+
+// httpClient is shared by all providers; local models can be slow, so the timeout is generous
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+// FromEnv returns a client for the first configured provider in preferred order.
+//
+// When LLM_BASE_URL is set the server runs in offline mode: every AI feature is
+// served by the self-hosted endpoint and cloud providers are never contacted.
+// AI_PROVIDER=none disables AI entirely. Returns ErrNoProvider when nothing is
+// configured so callers can fall back to deterministic logic.
+func FromEnv(preferred ...Provider) (Client, error) {
+	forced := Provider(strings.ToLower(strings.TrimSpace(os.Getenv("AI_PROVIDER"))))
+	if forced == ProviderNone {
+		return nil, ErrNoProvider
+	}
+
+	if baseURL := os.Getenv("LLM_BASE_URL"); baseURL != "" {
+		return newLocalClient(baseURL, os.Getenv("LLM_MODEL"), os.Getenv("LLM_API_KEY")), nil
+	}
+	if forced == ProviderLocal {
+		return nil, ErrNoProvider
+	}
+
+	if forced != "" {
+		preferred = []Provider{forced}
+	}
+
+	for _, p := range preferred {
+		switch p {
+		case ProviderGemini:
+			if key := os.Getenv("GEMINI_API_KEY"); key != "" {
+				return newGeminiClient(key, os.Getenv("GEMINI_MODEL")), nil
+			}
+		case ProviderOpenAI:
+			if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+				return newOpenAIClient(key, os.Getenv("OPENAI_MODEL")), nil
+			}
+		}
+	}
+
+	return nil, ErrNoProvider
+}
+
+// ActiveProvider reports which provider FromEnv would pick, or ProviderNone
+func ActiveProvider(preferred ...Provider) Provider {
+	client, err := FromEnv(preferred...)
+	if err != nil {
+		return ProviderNone
+	}
+	return client.Provider()
+}
+
+// StripCodeFence removes a surrounding markdown code block (```json ... ```) from model output
+func StripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+
+	// Remove opening code fence
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 {
+		lines = lines[1:] // Remove first line (```json or ```)
+	}
+	// Remove closing code fence
+	if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultGeminiModel = "gemini-2.0-flash"
+
+// Gemini API structures
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	Contents []geminiContent `json:"contents"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiClient calls the Google Gemini generateContent API
+type geminiClient struct {
+	apiKey string
+	model  string
+}
+
+func newGeminiClient(apiKey, model string) *geminiClient {
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &geminiClient{apiKey: apiKey, model: model}
+}
+
+func (g *geminiClient) Provider() Provider { return ProviderGemini }
+
+func (g *geminiClient) Model() string { return g.model }
+
+// Complete sends the request to Gemini. Gemini has no system role, so the
+// system instructions are prepended to the prompt.
+func (g *geminiClient) Complete(r Request) (*Response, error) {
+	resp, err := g.send("generateContent", r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	return &Response{
+		Text:     geminiResp.Candidates[0].Content.Parts[0].Text,
+		Provider: ProviderGemini,
+		Model:    g.model,
+		Usage: Usage{
+			PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
+		},
+	}, nil
+}
+
+// Stream sends the request to Gemini's streaming endpoint, calling onText as the answer arrives
+func (g *geminiClient) Stream(r Request, onText func(text string)) (*Response, error) {
+	resp, err := g.send("streamGenerateContent", r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Response{Provider: ProviderGemini, Model: g.model}
+	var text strings.Builder
+	err = readEvents(resp.Body, func(data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to parse Gemini stream: %w", err)
+		}
+		// Usage is reported so far with every chunk; the last one is the total
+		result.Usage = Usage{
+			PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+			CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+		}
+		if len(chunk.Candidates) > 0 && len(chunk.Candidates[0].Content.Parts) > 0 {
+			text.WriteString(chunk.Candidates[0].Content.Parts[0].Text)
+			onText(text.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	result.Text = text.String()
+	return result, nil
+}
+
+// send calls a Gemini method, returning the response once the status is OK. Gemini
+// has no system role, so the system instructions are prepended to the prompt.
+func (g *geminiClient) send(method string, r Request) (*http.Response, error) {
+	text := r.Prompt
+	if r.System != "" {
+		text = r.System + "\n\n" + r.Prompt
+	}
+
+	reqBody := geminiRequest{
+		Contents: []geminiContent{{Parts: []geminiPart{{Text: text}}}},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:%s?key=%s", g.model, method, g.apiKey)
+	if method == "streamGenerateContent" {
+		url += "&alt=sse"
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Gemini API: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if isGeminiQuotaError(resp.StatusCode, body) {
+		return nil, ErrQuotaExhausted
+	}
+	return nil, fmt.Errorf("Gemini API error (status %d): %s", resp.StatusCode, string(body))
+}
+
+// isGeminiQuotaError checks if an error response indicates Gemini quota exhaustion
+func isGeminiQuotaError(statusCode int, body []byte) bool {
+	// Check for 429 status code (rate limit/quota exceeded)
+	if statusCode == 429 {
+		return true
+	}
+
+	// Check for common quota-related error messages in response body
+	bodyStr := strings.ToLower(string(body))
+	quotaKeywords := []string{
+		"quota",
+		"resource_exhausted",
+		"rate limit",
+		"rate_limit",
+		"quota exceeded",
+		"quota_exceeded",
+	}
+
+	for _, keyword := range quotaKeywords {
+		if strings.Contains(bodyStr, keyword) {
+			return true
+		}
+	}
+
+	// Check for specific Gemini error structure
+	var geminiError struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &geminiError); err == nil {
+		errorMsg := strings.ToLower(geminiError.Error.Message)
+		errorStatus := strings.ToLower(geminiError.Error.Status)
+		for _, keyword := range quotaKeywords {
+			if strings.Contains(errorMsg, keyword) || strings.Contains(errorStatus, keyword) {
+				return true
+			}
+		}
+		// Check for RESOURCE_EXHAUSTED status
+		if errorStatus == "resource_exhausted" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIModel   = "gpt-4"
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+// OpenAI API structures (also spoken by llama.cpp, Ollama, vLLM and LM Studio)
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []openAIMessage      `json:"messages"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIChunk is one server-sent event of a streamed completion
+type openAIChunk struct {
+	Choices []struct {
+		Delta openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIClient calls an OpenAI-compatible /chat/completions endpoint
+type openAIClient struct {
+	provider Provider
+	baseURL  string
+	apiKey   string
+	model    string
+}
+
+func newOpenAIClient(apiKey, model string) *openAIClient {
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &openAIClient{provider: ProviderOpenAI, baseURL: defaultOpenAIBaseURL, apiKey: apiKey, model: model}
+}
+
+// newLocalClient points at a self-hosted endpoint, e.g. http://localhost:11434/v1.
+// The API key is optional since most local servers don't check it.
+func newLocalClient(baseURL, model, apiKey string) *openAIClient {
+	if model == "" {
+		model = "default"
+	}
+	return &openAIClient{
+		provider: ProviderLocal,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+	}
+}
+
+func (o *openAIClient) Provider() Provider { return o.provider }
+
+func (o *openAIClient) Model() string { return o.model }
+
+// Complete sends the request as a system + user chat
+func (o *openAIClient) Complete(r Request) (*Response, error) {
+	resp, err := o.send(openAIRequest{Model: o.model, Messages: chatMessages(r)})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", o.provider, err)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", o.provider)
+	}
+
+	return &Response{
+		Text:     openAIResp.Choices[0].Message.Content,
+		Provider: o.provider,
+		Model:    o.model,
+		Usage: Usage{
+			PromptTokens:     openAIResp.Usage.PromptTokens,
+			CompletionTokens: openAIResp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// Stream sends the request with streaming on, calling onText as the answer arrives
+func (o *openAIClient) Stream(r Request, onText func(text string)) (*Response, error) {
+	resp, err := o.send(openAIRequest{
+		Model:         o.model,
+		Messages:      chatMessages(r),
+		Stream:        true,
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Response{Provider: o.provider, Model: o.model}
+	var text strings.Builder
+	err = readEvents(resp.Body, func(data []byte) error {
+		var chunk openAIChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to parse %s stream: %w", o.provider, err)
+		}
+		if chunk.Usage != nil {
+			result.Usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+			onText(text.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response from %s", o.provider)
+	}
+
+	result.Text = text.String()
+	return result, nil
+}
+
+// send posts a chat request, returning the response once the status is OK
+func (o *openAIClient) send(payload openAIRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", o.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s API: %w", o.provider, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrQuotaExhausted
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return nil, fmt.Errorf("%s API error (status %d): %s", o.provider, resp.StatusCode, string(body))
+}
+
+// chatMessages turns a request into a system + user chat
+func chatMessages(r Request) []openAIMessage {
+	messages := make([]openAIMessage, 0, 2)
+	if r.System != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: r.System})
+	}
+	return append(messages, openAIMessage{Role: "user", Content: r.Prompt})
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// piiPattern names a kind of personal data and how to find it
+type piiPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// Order matters: more specific patterns run first so an SSN isn't half-matched as a phone number
+var piiPatterns = []piiPattern{
+	{"EMAIL", regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{"SSN", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"EIN", regexp.MustCompile(`\b\d{2}-\d{7}\b`)},
+	{"PHONE", regexp.MustCompile(`(\+?1[\s.\-]?)?(\(\d{3}\)\s?|\b\d{3}[\s.\-])\d{3}[\s.\-]\d{4}\b`)},
+	{"ACCOUNT", regexp.MustCompile(`\b\d{9,17}\b`)},
+}
+
+// redactionNote tells the model how to treat tokens so it doesn't mistake them for placeholders
+const redactionNote = "Text wrapped in « » (for example «EMAIL_1») is redacted personal data that has already been filled in. It is NOT a placeholder; copy tokens exactly if you need to reference them."
+
+// Redactor replaces personal data with reversible tokens before text leaves the server
+type Redactor struct {
+	values   []string          // Known sensitive values, e.g. names from answers
+	tokens   map[string]string // token -> original
+	byValue  map[string]string // original -> token
+	counters map[string]int
+}
+
+// NewRedactor creates a redactor that also masks the given literal values
+func NewRedactor(values ...string) *Redactor {
+	r := &Redactor{
+		tokens:   make(map[string]string),
+		byValue:  make(map[string]string),
+		counters: make(map[string]int),
+	}
+	for _, v := range values {
+		// Very short values ("1", "CA") would mask unrelated text
+		if v = strings.TrimSpace(v); len(v) >= 3 {
+			r.values = append(r.values, v)
+		}
+	}
+	// Longest first so "Acme Inc." wins over "Acme"
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r
+}
+
+// Redact masks known values and detected PII in text
+func (r *Redactor) Redact(text string) string {
+	for _, v := range r.values {
+		if strings.Contains(text, v) {
+			text = strings.ReplaceAll(text, v, r.token("VALUE", v))
+		}
+	}
+	for _, p := range piiPatterns {
+		text = p.re.ReplaceAllStringFunc(text, func(match string) string {
+			return r.token(p.kind, match)
+		})
+	}
+	return text
+}
+
+// Restore puts the original values back in place of tokens
+func (r *Redactor) Restore(text string) string {
+	if len(r.tokens) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(r.tokens)*2)
+	for token, original := range r.tokens {
+		pairs = append(pairs, token, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Count returns how many distinct values were redacted
+func (r *Redactor) Count() int {
+	return len(r.tokens)
+}
+
+func (r *Redactor) token(kind, original string) string {
+	if token, ok := r.byValue[original]; ok {
+		return token
+	}
+	r.counters[kind]++
+	token := fmt.Sprintf("«%s_%d»", kind, r.counters[kind])
+	r.tokens[token] = original
+	r.byValue[original] = token
+	return token
+}
+
+// RedactionEnabled reports whether prompts should be redacted (on unless PII_REDACTION=off)
+func RedactionEnabled() bool {
+	switch strings.ToLower(os.Getenv("PII_REDACTION")) {
+	case "off", "false", "0":
+		return false
+	}
+	return true
+}
+
+// redactingClient wraps a Client so prompts are redacted and responses restored
+type redactingClient struct {
+	Client
+	values []string
+}
+
+// WithRedaction wraps client so that PII and the given values never reach the provider.
+// Each call gets a fresh Redactor, so tokens never leak between requests.
+func WithRedaction(client Client, values ...string) Client {
+	if !RedactionEnabled() {
+		return client
+	}
+	return &redactingClient{Client: client, values: values}
+}
+
+func (rc *redactingClient) Complete(req Request) (*Response, error) {
+	r := NewRedactor(rc.values...)
+	req.Prompt = r.Redact(req.Prompt)
+	if r.Count() > 0 {
+		req.System = strings.TrimSpace(req.System + "\n\n" + redactionNote)
+	}
+
+	resp, err := rc.Client.Complete(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Text = r.Restore(resp.Text)
+	return resp, nil
+}
+
+// Stream redacts the prompt like Complete and restores the text as it arrives
+func (rc *redactingClient) Stream(req Request, onText func(text string)) (*Response, error) {
+	r := NewRedactor(rc.values...)
+	req.Prompt = r.Redact(req.Prompt)
+	if r.Count() > 0 {
+		req.System = strings.TrimSpace(req.System + "\n\n" + redactionNote)
+	}
+
+	resp, err := Stream(rc.Client, req, func(text string) { onText(r.Restore(text)) })
+	if err != nil {
+		return nil, err
+	}
+	resp.Text = r.Restore(resp.Text)
+	return resp, nil
+}
@@ -0,0 +1,25 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRedactorRoundTrip tests that PII is masked and restored exactly
+func TestRedactorRoundTrip(t *testing.T) {
+	text := "Jane Doe (jane@example.com, 415-555-0134, SSN 123-45-6789, EIN 12-3456789) signs for [Company Name]."
+
+	r := NewRedactor("Jane Doe")
+	redacted := r.Redact(text)
+
+	assert.NotContains(t, redacted, "Jane Doe")
+	assert.NotContains(t, redacted, "jane@example.com")
+	assert.NotContains(t, redacted, "415-555-0134")
+	assert.NotContains(t, redacted, "123-45-6789")
+	assert.NotContains(t, redacted, "12-3456789")
+	assert.Contains(t, redacted, "[Company Name]")
+	assert.Equal(t, 5, r.Count())
+
+	assert.Equal(t, text, r.Restore(redacted))
+}
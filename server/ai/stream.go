@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Streamer is implemented by clients that can return a completion while it is
+// being generated
+type Streamer interface {
+	// Stream runs a completion, calling onText with all the text received so far
+	// each time more arrives
+	Stream(req Request, onText func(text string)) (*Response, error)
+}
+
+// Stream runs a completion through client, streaming when it can. Clients that
+// can't stream call onText once with the whole answer.
+func Stream(client Client, req Request, onText func(text string)) (*Response, error) {
+	if s, ok := client.(Streamer); ok {
+		return s.Stream(req, onText)
+	}
+
+	resp, err := client.Complete(req)
+	if err != nil {
+		return nil, err
+	}
+	onText(resp.Text)
+	return resp, nil
+}
+
+// maxEventSize bounds one server-sent event line from a provider
+const maxEventSize = 1 << 20
+
+// readEvents calls fn with the data of each server-sent event in body, stopping
+// at the OpenAI-style [DONE] marker or the end of the body
+func readEvents(body io.Reader, fn func(data []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return nil
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAIStream tests that streamed deltas are reported as they arrive and usage is read from the last chunk
+func TestOpenAIStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{`{"a": `, `1}`} {
+			fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 12, \"completion_tokens\": 3}}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var seen []string
+	resp, err := Stream(newLocalClient(server.URL, "", ""), Request{Prompt: "hi"}, func(text string) {
+		seen = append(seen, text)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"a": `, `{"a": 1}`}, seen)
+	assert.Equal(t, `{"a": 1}`, resp.Text)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 3}, resp.Usage)
+}
@@ -0,0 +1,24 @@
+package docx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/profiles"
+)
+
+// TestBuildDetectionPromptUsesProfile tests that profile rules reach the detection prompt
+func TestBuildDetectionPromptUsesProfile(t *testing.T) {
+	lease, err := profiles.Get("lease")
+	require.NoError(t, err)
+
+	prompt, err := buildDetectionPrompt("The Tenant shall pay $[______] monthly.", lease)
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt.System, "Lease legal documents")
+	assert.Contains(t, prompt.Prompt, "- Exhibit and rider references like [Exhibit A] or [Rider 1]")
+	assert.Contains(t, prompt.Prompt, "Monthly Rent, Security Deposit")
+	assert.Contains(t, prompt.Prompt, "The Tenant shall pay $[______] monthly.")
+	assert.NotContains(t, prompt.Prompt, "Purchase Amount\", identify")
+}
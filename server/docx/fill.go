@@ -5,15 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/nguyenthenguyen/docx"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/prompts"
 )
 
-// FillDocument replaces placeholders with answers in the document using AI-powered smart replacement
-func FillDocument(docBytes []byte, answers map[string]string) ([]byte, error) {
+// FillDocument replaces placeholders with answers in the document using AI-powered smart replacement.
+// A nil client skips the AI mapping and uses the standard placeholder formats. exact gives
+// the literal placeholder text for fields whose placeholder is known (e.g. added by hand).
+func FillDocument(docBytes []byte, answers map[string]string, exact map[string]string, client ai.Client) ([]byte, error) {
 	// Write bytes to temp file (nguyenthenguyen/docx needs a file path)
 	tmpFile, err := os.CreateTemp("", "docx-*.docx")
 	if err != nil {
@@ -40,17 +45,25 @@ func FillDocument(docBytes []byte, answers map[string]string) ([]byte, error) {
 	// Get document content for smart replacement
 	docText := editable.GetContent()
 
-	// Use AI to create smart placeholder mappings
-	placeholderMap, err := createSmartPlaceholderMap(docText, answers)
-	if err != nil {
-		// Fallback to simple replacement if AI fails
-		fmt.Printf("AI replacement failed, using simple replacement: %v\n", err)
-		placeholderMap = createSimplePlaceholderMap(answers)
+	// Use AI to create smart placeholder mappings, falling back to simple replacement
+	placeholderMap := createSimplePlaceholderMap(answers)
+	if client != nil {
+		smart, err := createSmartPlaceholderMap(client, docText, answers)
+		if err != nil {
+			log.Printf("AI replacement failed, using simple replacement: %v", err)
+		} else {
+			placeholderMap = smart
+		}
+	}
+	for field, placeholder := range exact {
+		if answer, ok := answers[field]; ok && placeholder != "" {
+			placeholderMap[placeholder] = answer
+		}
 	}
 
 	// Replace placeholders using the mapping
-	for placeholder, answer := range placeholderMap {
-		editable.Replace(placeholder, answer, -1)
+	for _, placeholder := range replacementOrder(placeholderMap) {
+		editable.Replace(placeholder, placeholderMap[placeholder], -1)
 	}
 
 	// Write the modified document to a new temp file
@@ -76,6 +89,38 @@ func FillDocument(docBytes []byte, answers map[string]string) ([]byte, error) {
 	return filledBytes, nil
 }
 
+// replacementOrder adds symbol-aware variants so a "$1,000" answer in a "$[Amount]"
+// template doesn't come out as "$$1,000" (likewise "20%" in "[Rate]%"), then orders
+// placeholders longest first so the variants are replaced before the bare placeholder
+func replacementOrder(placeholderMap map[string]string) []string {
+	variants := make(map[string]string)
+	for placeholder, answer := range placeholderMap {
+		if strings.HasPrefix(answer, "$") {
+			variants["$"+placeholder] = answer
+		}
+		if strings.HasSuffix(answer, "%") {
+			variants[placeholder+"%"] = answer
+		}
+	}
+	for variant, answer := range variants {
+		if _, ok := placeholderMap[variant]; !ok {
+			placeholderMap[variant] = answer
+		}
+	}
+
+	order := make([]string, 0, len(placeholderMap))
+	for placeholder := range placeholderMap {
+		order = append(order, placeholder)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if len(order[i]) != len(order[j]) {
+			return len(order[i]) > len(order[j])
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
 // createSimplePlaceholderMap creates basic placeholder variations for each field
 func createSimplePlaceholderMap(answers map[string]string) map[string]string {
 	placeholders := make(map[string]string)
@@ -104,20 +149,21 @@ func createSimplePlaceholderMap(answers map[string]string) map[string]string {
 }
 
 // createSmartPlaceholderMap uses AI to map field names to exact placeholder strings in the document
-func createSmartPlaceholderMap(docText string, answers map[string]string) (map[string]string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY not set")
+func createSmartPlaceholderMap(client ai.Client, docText string, answers map[string]string) (map[string]string, error) {
+	if client == nil {
+		return nil, ai.ErrNoProvider
 	}
 
-	// Build list of fields to find
+	// Build list of fields to find, keeping answer values back for redaction
 	fields := make([]string, 0, len(answers))
-	for field := range answers {
+	values := make([]string, 0, len(answers))
+	for field, answer := range answers {
 		fields = append(fields, field)
+		values = append(values, answer)
 	}
 
 	// Use AI to find exact placeholders
-	mapping, err := findPlaceholdersWithAI(docText, fields, apiKey)
+	mapping, err := findPlaceholdersWithAI(ai.WithRedaction(client, values...), docText, fields)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +180,7 @@ func createSmartPlaceholderMap(docText string, answers map[string]string) (map[s
 }
 
 // findPlaceholdersWithAI uses AI to find the exact placeholder text for each field
-func findPlaceholdersWithAI(docText string, fields []string, apiKey string) (map[string]string, error) {
+func findPlaceholdersWithAI(client ai.Client, docText string, fields []string) (map[string]string, error) {
 	// Truncate if needed
 	maxLength := 10000
 	if len(docText) > maxLength {
@@ -142,113 +188,18 @@ func findPlaceholdersWithAI(docText string, fields []string, apiKey string) (map
 	}
 
 	fieldsJSON, _ := json.Marshal(fields)
-	prompt := fmt.Sprintf(`Given this document text and a list of field names, find the EXACT placeholder text in the document that should be replaced for each field.
-
-Fields to find: %s
-
-Document text:
-%s
-
-For each field, identify the exact placeholder text as it appears in the document. This could be:
-- [Field Name] format
-- {{field_name}} format
-- $[___________] (underscore blanks)
-- Any other placeholder format
-
-Return a JSON object mapping each field name to its exact placeholder text. For example:
-{
-  "company_name": "[COMPANY]",
-  "investor_name": "[Investor Name]",
-  "purchase_amount": "$[_____________]"
-}
-
-Important: Return the EXACT text as it appears in the document, including brackets, dollar signs, underscores, etc.
-
-Do not include any explanation, just the JSON object.`, fieldsJSON, docText)
-
-	// Make Gemini request
-	type geminiContent struct {
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	}
-
-	type geminiRequest struct {
-		Contents []geminiContent `json:"contents"`
-	}
-
-	reqBody := geminiRequest{
-		Contents: []geminiContent{
-			{
-				Parts: []struct {
-					Text string `json:"text"`
-				}{
-					{Text: "You are an expert at analyzing documents and finding placeholders. Always respond with valid JSON only.\n\n" + prompt},
-				},
-			},
-		},
-	}
-
-	jsonData, _ := json.Marshal(reqBody)
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=%s", apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	prompt, err := prompts.Render(prompts.Mapping, struct{ FieldsJSON, DocText string }{string(fieldsJSON), docText})
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := client.Complete(ai.Request{System: prompt.System, Prompt: prompt.Prompt})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		if isGeminiQuotaError(resp.StatusCode, body) {
-			return nil, ErrGeminiQuotaExhausted
-		}
-		return nil, fmt.Errorf("Gemini API error: %s", string(body))
-	}
-
-	// Parse Gemini response
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return nil, err
-	}
-
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from Gemini")
-	}
-
-	// Get the content and strip markdown code blocks if present
-	content := geminiResp.Candidates[0].Content.Parts[0].Text
-	content = strings.TrimSpace(content)
-	if strings.HasPrefix(content, "```") {
-		// Remove opening code fence
-		lines := strings.Split(content, "\n")
-		if len(lines) > 0 {
-			lines = lines[1:] // Remove first line (```json or ```)
-		}
-		// Remove closing code fence
-		if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
-			lines = lines[:len(lines)-1]
-		}
-		content = strings.Join(lines, "\n")
-		content = strings.TrimSpace(content)
-	}
+	// Strip markdown code blocks if present
+	content := ai.StripCodeFence(resp.Text)
 
 	// Parse the mapping
 	var mapping map[string]string
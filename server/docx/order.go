@@ -0,0 +1,104 @@
+package docx
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters of document text kept on each side of a placeholder for type inference
+const (
+	contextBefore = 40
+	contextAfter  = 20
+)
+
+// FieldContext is the document text immediately around a field's first placeholder
+type FieldContext struct {
+	Before string
+	After  string
+}
+
+// fieldSpan is where a field first appears in the (space-normalized) document text
+type fieldSpan struct {
+	start, end int
+}
+
+// locateFields finds where each field's placeholder first appears in the document
+// text. Fields whose placeholder can't be located (e.g. AI-named fields) are matched
+// by their label ("company name") as whole words instead, so "rate" isn't found in
+// "corporate"; fields found neither way are left out.
+func locateFields(text string, fields []string) map[string]fieldSpan {
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f] = true
+	}
+
+	spans := make(map[string]fieldSpan, len(fields))
+	for _, sp := range findPlaceholderSpans(text, known) {
+		if _, seen := spans[sp.field]; !seen {
+			spans[sp.field] = fieldSpan{sp.start, sp.end}
+		}
+	}
+
+	for _, f := range fields {
+		if _, found := spans[f]; found {
+			continue
+		}
+		for _, label := range []string{strings.ReplaceAll(f, "_", " "), f} {
+			// Matching case-insensitively on text itself keeps the offsets valid for it
+			label := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(label) + `\b`)
+			if loc := label.FindStringIndex(text); loc != nil {
+				spans[f] = fieldSpan{loc[0], loc[1]}
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// orderByPosition sorts fields by where their placeholder first appears in the document
+// text. Any that can't be located keep their relative order at the end.
+func orderByPosition(text string, fields []string) []string {
+	position := locateFields(normalizeSpace(text), fields)
+
+	ordered := append([]string{}, fields...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, iFound := position[ordered[i]]
+		pj, jFound := position[ordered[j]]
+		if iFound != jFound {
+			return iFound
+		}
+		return iFound && pi.start < pj.start
+	})
+	return ordered
+}
+
+// fieldContexts returns the text around each located field, cut at line breaks
+// so a heading or previous paragraph doesn't leak into the context
+func fieldContexts(text string, fields []string) map[string]FieldContext {
+	text = normalizeSpace(text)
+
+	contexts := make(map[string]FieldContext, len(fields))
+	for field, sp := range locateFields(text, fields) {
+		from, to := max(0, sp.start-contextBefore), min(len(text), sp.end+contextAfter)
+		// Don't cut through a multi-byte character such as a curly quote
+		for from < sp.start && !utf8.RuneStart(text[from]) {
+			from++
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to--
+		}
+
+		before := text[from:sp.start]
+		if i := strings.LastIndex(before, "\n"); i >= 0 {
+			before = before[i+1:]
+		}
+		after := text[sp.end:to]
+		if i := strings.Index(after, "\n"); i >= 0 {
+			after = after[:i]
+		}
+		contexts[field] = FieldContext{Before: before, After: after}
+	}
+	return contexts
+}
@@ -0,0 +1,71 @@
+package docx
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/profiles"
+)
+
+var (
+	xmlParagraphEnd = regexp.MustCompile(`</w:p>`)
+	xmlTag          = regexp.MustCompile(`<[^>]+>`)
+
+	// {{client_name}}
+	curlyPlaceholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+	// [Company Name] or $[Purchase Amount]
+	bracketPlaceholder = regexp.MustCompile(`\$?\[([^\[\]]+)\]`)
+
+	// $[__________] (the "Purchase Amount")
+	blankWithLabel = regexp.MustCompile(`\$?\[_{3,}\]\s*\(the\s+["“]([^"”]+)["”]\)`)
+
+	// Static bracketed text that is not a placeholder: [Section 1(d)], [1], [a], [iv], [1(a)]
+	sectionReference = regexp.MustCompile(`(?i)^(section|sec\.|article|clause|exhibit|schedule|annex|appendix|§)\s`)
+	numberedMarker   = regexp.MustCompile(`^\d+(\([a-z0-9]+\))*$`)
+	letterMarker     = regexp.MustCompile(`^[a-z]$|^[ivxlcdm]+$`)
+	underscoreBlank  = regexp.MustCompile(`^_+$`)
+)
+
+// plainText strips WordprocessingML markup from document XML, keeping one line per paragraph
+func plainText(docXML string) string {
+	text := xmlParagraphEnd.ReplaceAllString(docXML, "\n")
+	text = xmlTag.ReplaceAllString(text, "")
+	return html.UnescapeString(text)
+}
+
+// detectFieldsWithPatterns is the deterministic fallback used when no AI provider
+// is configured. It finds {{field}} and [Field Name] placeholders, skips section
+// and footnote references plus the profile's static text, and names underscore
+// blanks from a following (the "Label") definition.
+func detectFieldsWithPatterns(docXML string, profile *profiles.Profile) []string {
+	text := plainText(docXML)
+
+	var names []string
+	for _, m := range curlyPlaceholder.FindAllStringSubmatch(text, -1) {
+		names = append(names, m[1])
+	}
+	for _, m := range blankWithLabel.FindAllStringSubmatch(text, -1) {
+		names = append(names, m[1])
+	}
+	for _, m := range bracketPlaceholder.FindAllStringSubmatch(text, -1) {
+		if isPlaceholderText(m[1]) && !profile.Excludes(strings.TrimSpace(m[1])) {
+			names = append(names, m[1])
+		}
+	}
+
+	return uniqueFields(names)
+}
+
+// isPlaceholderText reports whether bracketed text looks like a fillable field
+func isPlaceholderText(inner string) bool {
+	inner = strings.TrimSpace(inner)
+	if inner == "" || underscoreBlank.MatchString(inner) {
+		return false
+	}
+	if sectionReference.MatchString(inner) || numberedMarker.MatchString(inner) || letterMarker.MatchString(inner) {
+		return false
+	}
+	return strings.ContainsAny(strings.ToLower(inner), "abcdefghijklmnopqrstuvwxyz")
+}
@@ -0,0 +1,62 @@
+package docx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/you/lexsy-mvp/server/profiles"
+)
+
+// TestDetectFieldsWithPatterns tests the deterministic placeholder detector
+func TestDetectFieldsWithPatterns(t *testing.T) {
+	docXML := `<w:p><w:r><w:t>This SAFE is issued by [Company Name] to {{investor_name}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>in exchange for $[_____________] (the &quot;Purchase Amount&quot;)</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>as described in [Section 1(d)] and note [1], clause [iv].</w:t></w:r></w:p>`
+
+	fields := orderByPosition(plainText(docXML), detectFieldsWithPatterns(docXML, profiles.Classify(plainText(docXML))))
+
+	assert.Equal(t, []string{"company_name", "investor_name", "purchase_amount"}, fields)
+}
+
+// TestDetectFieldsWithPatternsProfileExclusions tests that profile rules drop static bracketed text
+func TestDetectFieldsWithPatternsProfileExclusions(t *testing.T) {
+	docXML := `<w:p><w:r><w:t>Invoice [Invoice Number] due [Due Date]. Bill to [Client Name].</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>[Qty] [Description] [Unit Price] [Amount]. Subtotal and total amount due on receipt.</w:t></w:r></w:p>`
+
+	profile := profiles.Classify(plainText(docXML))
+	assert.Equal(t, "invoice", profile.Name)
+
+	fields := orderByPosition(plainText(docXML), detectFieldsWithPatterns(docXML, profile))
+	assert.Equal(t, []string{"invoice_number", "due_date", "client_name"}, fields)
+}
+
+// TestOrderByPosition tests that fields follow the document rather than the alphabet
+func TestOrderByPosition(t *testing.T) {
+	text := "This SAFE is issued by [Company Name] to {{investor_name}} at a Valuation Cap of $[Valuation Cap].\n" +
+		"Signed on the date below by the company signatory."
+
+	fields := orderByPosition(text, []string{"valuation_cap", "signatory", "unknown_field", "company_name", "investor_name"})
+
+	assert.Equal(t, []string{"company_name", "investor_name", "valuation_cap", "signatory", "unknown_field"}, fields)
+
+	// Labels only match whole words, not "age" in "agency" or "rate" in "corporate"
+	text = "The agency and the corporate client set the Rate before the Age."
+	assert.Equal(t, []string{"rate", "age"}, orderByPosition(text, []string{"age", "rate"}))
+	assert.Equal(t, FieldContext{Before: "agency and the corporate client set the ", After: " before the Age."}, fieldContexts(text, []string{"rate"})["rate"])
+}
+
+// TestFieldContexts tests that each field gets the text around its first placeholder
+func TestFieldContexts(t *testing.T) {
+	text := "SAFE\nThis SAFE is dated as of [Effective Date] and issued by [Company Name]\n" +
+		"in exchange for $[Investment] (the “Purchase Amount”)."
+
+	contexts := fieldContexts(text, []string{"effective_date", "company_name", "investment", "missing"})
+
+	assert.Equal(t, FieldContext{Before: "This SAFE is dated as of ", After: " and issued by [Comp"}, contexts["effective_date"])
+	assert.Equal(t, FieldContext{Before: "in exchange for $", After: " (the “Purchase Am"}, contexts["investment"])
+	assert.NotContains(t, contexts, "missing")
+
+	profile, _ := profiles.Get(profiles.Generic)
+	ctx := contexts["investment"]
+	assert.Equal(t, "currency", profile.ExplainFieldType("investment", ctx.Before, ctx.After).Type)
+}
@@ -0,0 +1,144 @@
+package docx
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// anchorLength is how much literal text on each side of a placeholder is used to locate its value
+	anchorLength = 40
+
+	// maxRecoveredLength rejects "values" that swallowed a large edited passage
+	maxRecoveredLength = 500
+)
+
+var (
+	horizontalSpace = regexp.MustCompile(`[ \t\x{00a0}]+`)
+
+	// [__________] (the "Purchase Amount"), capturing the blank and the label separately
+	labelledBlank = regexp.MustCompile(`(\[_{3,}\])\s*\(the\s+["“]([^"”]+)["”]\)`)
+
+	// [Company Name] without a leading $, which stays part of the surrounding text
+	bracketSpan = regexp.MustCompile(`\[([^\[\]]+)\]`)
+)
+
+// placeholderSpan is one placeholder occurrence in template text
+type placeholderSpan struct {
+	field      string
+	start, end int
+}
+
+// RecoverAnswers diffs a template against a filled copy of it and returns the value
+// written in place of each field's placeholder. Each placeholder is located in the
+// filled text by the literal text around it, so edits elsewhere in the document
+// don't throw the match off. Fields whose placeholders can't be found or anchored
+// are returned as unresolved.
+func RecoverAnswers(templateText, filledText string, fields []string) (map[string]string, []string) {
+	templateText = normalizeSpace(templateText)
+	filledText = normalizeSpace(filledText)
+
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f] = true
+	}
+
+	spans := findPlaceholderSpans(templateText, known)
+	answers := make(map[string]string)
+
+	cursor := 0
+	for i, sp := range spans {
+		if _, done := answers[sp.field]; done {
+			continue
+		}
+
+		// Anchor on literal text between this placeholder and its neighbours
+		leftStart := max(sp.start-anchorLength, 0)
+		if i > 0 {
+			leftStart = max(leftStart, spans[i-1].end)
+		}
+		rightEnd := min(sp.end+anchorLength, len(templateText))
+		if i < len(spans)-1 {
+			rightEnd = min(rightEnd, spans[i+1].start)
+		}
+		left := templateText[leftStart:sp.start]
+		right := templateText[sp.end:rightEnd]
+
+		// Two placeholders back to back can't be told apart
+		if strings.TrimSpace(right) == "" {
+			continue
+		}
+
+		valueStart := cursor
+		if left != "" {
+			idx := strings.Index(filledText[cursor:], left)
+			if idx < 0 {
+				continue
+			}
+			valueStart = cursor + idx + len(left)
+		}
+		idx := strings.Index(filledText[valueStart:], right)
+		if idx < 0 {
+			continue
+		}
+		valueEnd := valueStart + idx
+
+		value := strings.TrimSpace(filledText[valueStart:valueEnd])
+		placeholder := templateText[sp.start:sp.end]
+		if value == "" || value == placeholder || len(value) > maxRecoveredLength {
+			continue
+		}
+
+		answers[sp.field] = value
+		cursor = valueEnd
+	}
+
+	var unresolved []string
+	for _, f := range fields {
+		if _, ok := answers[f]; !ok {
+			unresolved = append(unresolved, f)
+		}
+	}
+
+	return answers, unresolved
+}
+
+// findPlaceholderSpans locates placeholders for known fields, in document order
+func findPlaceholderSpans(text string, known map[string]bool) []placeholderSpan {
+	var spans []placeholderSpan
+	add := func(name string, start, end int) {
+		if field := NormalizeFieldName(name); known[field] {
+			spans = append(spans, placeholderSpan{field: field, start: start, end: end})
+		}
+	}
+
+	for _, m := range curlyPlaceholder.FindAllStringSubmatchIndex(text, -1) {
+		add(text[m[2]:m[3]], m[0], m[1])
+	}
+	for _, m := range labelledBlank.FindAllStringSubmatchIndex(text, -1) {
+		add(text[m[4]:m[5]], m[2], m[3])
+	}
+	for _, m := range bracketSpan.FindAllStringSubmatchIndex(text, -1) {
+		if inner := text[m[2]:m[3]]; isPlaceholderText(inner) {
+			add(inner, m[0], m[1])
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	// Drop overlaps (e.g. "[_____]" matched both as a labelled blank and a bracket)
+	result := spans[:0]
+	for _, sp := range spans {
+		if len(result) > 0 && sp.start < result[len(result)-1].end {
+			continue
+		}
+		result = append(result, sp)
+	}
+	return result
+}
+
+// normalizeSpace collapses runs of spaces and tabs so spacing differences don't break anchors
+func normalizeSpace(text string) string {
+	return horizontalSpace.ReplaceAllString(text, " ")
+}
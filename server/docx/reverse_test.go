@@ -0,0 +1,30 @@
+package docx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRecoverAnswers tests recovering field values from a filled copy of a template
+func TestRecoverAnswers(t *testing.T) {
+	template := "THIS SAFE is issued by [Company Name] to {{investor_name}}\n" +
+		"in exchange for $[_____________] (the \"Purchase Amount\") on or about [Date].\n" +
+		"Governing law: [State]."
+	filled := "THIS SAFE is issued by Acme  Robotics, Inc. to Jane Q. Investor\n" +
+		"in exchange for $250,000 (the \"Purchase Amount\") on or about March 1, 2024.\n" +
+		"Governing law: [State]."
+
+	fields := []string{"company_name", "investor_name", "purchase_amount", "date", "state", "valuation_cap"}
+	answers, unresolved := RecoverAnswers(template, filled, fields)
+
+	assert.Equal(t, map[string]string{
+		"company_name":    "Acme Robotics, Inc.",
+		"investor_name":   "Jane Q. Investor",
+		"purchase_amount": "250,000",
+		"date":            "March 1, 2024",
+	}, answers)
+
+	// [State] was left unfilled and valuation_cap has no placeholder in the text
+	assert.Equal(t, []string{"state", "valuation_cap"}, unresolved)
+}
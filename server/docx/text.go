@@ -0,0 +1,43 @@
+package docx
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/nguyenthenguyen/docx"
+)
+
+// readDocumentXML returns the raw document.xml content of a .docx
+func readDocumentXML(docBytes []byte) (string, error) {
+	// Write bytes to temp file (nguyenthenguyen/docx needs a file path)
+	tmpFile, err := os.CreateTemp("", "docx-*.docx")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err := io.Copy(tmpFile, bytes.NewReader(docBytes)); err != nil {
+		return "", err
+	}
+	tmpFile.Close() // Close before reading
+
+	// Read docx file
+	doc, err := docx.ReadDocxFile(tmpFile.Name())
+	if err != nil {
+		return "", err
+	}
+	defer doc.Close()
+
+	return doc.Editable().GetContent(), nil
+}
+
+// ExtractText returns the plain text of a .docx, one line per paragraph
+func ExtractText(docBytes []byte) (string, error) {
+	docXML, err := readDocumentXML(docBytes)
+	if err != nil {
+		return "", err
+	}
+	return plainText(docXML), nil
+}
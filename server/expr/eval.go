@@ -0,0 +1,260 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrEval = errors.New("evaluation error")
+
+// value is a float64, string, bool or time.Time. Answers come in as strings and are
+// converted on use, so "$5,000,000" works in arithmetic and "2024-03-01" in date math.
+type value any
+
+// Lookup returns the answer for a field name
+type Lookup func(field string) (string, error)
+
+// dateLayouts are accepted when reading dates from answers; the first is also the output format
+var dateLayouts = []string{"January 2, 2006", "2006-01-02", "Jan 2, 2006", "01/02/2006", "2 January 2006"}
+
+// Eval evaluates the expression and formats the result for a document:
+// whole numbers without decimals, dates as "January 2, 2006", booleans as Yes/No
+func (e *Expr) Eval(lookup Lookup) (string, error) {
+	v, err := eval(e.root, lookup)
+	if err != nil {
+		return "", err
+	}
+	return format(v), nil
+}
+
+func eval(n node, lookup Lookup) (value, error) {
+	switch n := n.(type) {
+	case literal:
+		return n.value, nil
+
+	case ref:
+		answer, err := lookup(n.name)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+
+	case unary:
+		x, err := eval(n.x, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "!" {
+			b, err := toBool(x)
+			return !b, err
+		}
+		f, err := toNumber(x)
+		return -f, err
+
+	case conditional:
+		c, err := eval(n.cond, lookup)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := toBool(c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return eval(n.then, lookup)
+		}
+		return eval(n.otherwise, lookup)
+
+	case binary:
+		return evalBinary(n, lookup)
+
+	case call:
+		args := make([]value, len(n.args))
+		for i, arg := range n.args {
+			v, err := eval(arg, lookup)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		v, err := functions[n.name](args)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", n.name, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: unknown expression", ErrEval)
+}
+
+func evalBinary(n binary, lookup Lookup) (value, error) {
+	l, err := eval(n.l, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logic
+	if n.op == "&&" || n.op == "||" {
+		lb, err := toBool(l)
+		if err != nil || lb == (n.op == "||") {
+			return lb, err
+		}
+		r, err := eval(n.r, lookup)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(r)
+	}
+
+	r, err := eval(n.r, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+":
+		// Numbers add, anything else concatenates
+		lf, lerr := toNumber(l)
+		rf, rerr := toNumber(r)
+		if lerr == nil && rerr == nil {
+			return lf + rf, nil
+		}
+		return joinText(l, r)
+
+	case "-", "*", "/", "%":
+		lf, err := toNumber(l)
+		if err != nil {
+			return nil, err
+		}
+		rf, err := toNumber(r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		}
+		if rf == 0 {
+			return nil, fmt.Errorf("%w: division by zero", ErrEval)
+		}
+		if n.op == "/" {
+			return lf / rf, nil
+		}
+		return math.Mod(lf, rf), nil
+
+	case "==", "!=":
+		equal := false
+		lf, lerr := toNumber(l)
+		rf, rerr := toNumber(r)
+		if lerr == nil && rerr == nil {
+			equal = lf == rf
+		} else {
+			equal = strings.EqualFold(strings.TrimSpace(format(l)), strings.TrimSpace(format(r)))
+		}
+		return equal == (n.op == "=="), nil
+
+	default:
+		cmp, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	}
+}
+
+// compare orders two numbers or two dates
+func compare(l, r value) (int, error) {
+	lf, lerr := toNumber(l)
+	rf, rerr := toNumber(r)
+	if lerr == nil && rerr == nil {
+		switch {
+		case lf < rf:
+			return -1, nil
+		case lf > rf:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	ld, lerr := toDate(l)
+	rd, rerr := toDate(r)
+	if lerr == nil && rerr == nil {
+		return ld.Compare(rd), nil
+	}
+	return 0, fmt.Errorf("%w: cannot compare %q and %q", ErrEval, format(l), format(r))
+}
+
+func toNumber(v value) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		s := strings.NewReplacer("$", "", ",", "", "%", "", " ", "").Replace(strings.TrimSpace(v))
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q is not a number", ErrEval, format(v))
+}
+
+func toDate(v value) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q is not a date", ErrEval, format(v))
+}
+
+func toBool(v value) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "yes", "y", "true", "1":
+			return true, nil
+		case "no", "n", "false", "0", "":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%w: %q is not yes or no", ErrEval, format(v))
+}
+
+func format(v value) string {
+	switch v := v.(type) {
+	case float64:
+		// Drop floating point noise such as 0.1+0.2 = 0.30000000000000004
+		return strconv.FormatFloat(math.Round(v*1e9)/1e9, 'f', -1, 64)
+	case bool:
+		if v {
+			return "Yes"
+		}
+		return "No"
+	case time.Time:
+		return v.Format(dateLayouts[0])
+	case string:
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,378 @@
+// Package expr implements the small expression language used for computed fields.
+// Expressions can only read answers by field name and call a fixed set of pure
+// functions, so they are safe to accept from users: there are no loops, no I/O and
+// the size and nesting of an expression are bounded.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxLength = 1000 // Longest accepted expression
+	maxDepth  = 64   // Deepest accepted nesting
+)
+
+var ErrSyntax = errors.New("syntax error")
+
+// Expr is a parsed expression
+type Expr struct {
+	source string
+	root   node
+}
+
+// Parse parses an expression such as
+//
+//	valuation_cap + purchase_amount
+//	add_months(start_date, term_months)
+//	has_discount == "yes" ? discount_rate + "%" : "N/A"
+func Parse(source string) (*Expr, error) {
+	if len(source) > maxLength {
+		return nil, fmt.Errorf("%w: expression is longer than %d characters", ErrSyntax, maxLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, tok.text, tok.pos)
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+// String returns the source the expression was parsed from
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Fields returns the field names the expression reads, in order of first use
+func (e *Expr) Fields() []string {
+	var fields []string
+	seen := make(map[string]bool)
+	walk(e.root, func(n node) {
+		if r, ok := n.(ref); ok && !seen[r.name] {
+			seen[r.name] = true
+			fields = append(fields, r.name)
+		}
+	})
+	return fields
+}
+
+// Nodes
+
+type node interface{}
+
+type (
+	literal struct{ value value }
+	ref     struct{ name string }
+	unary   struct {
+		op string
+		x  node
+	}
+	binary struct {
+		op   string
+		l, r node
+	}
+	conditional struct{ cond, then, otherwise node }
+	call        struct {
+		name string
+		args []node
+	}
+)
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case unary:
+		walk(n.x, fn)
+	case binary:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case conditional:
+		walk(n.cond, fn)
+		walk(n.then, fn)
+		walk(n.otherwise, fn)
+	case call:
+		for _, arg := range n.args {
+			walk(arg, fn)
+		}
+	}
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators, longest first so "<=" wins over "<"
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", ","}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, src[start:i], start})
+
+		case c == '"' || c == '\'':
+			start := i
+			var text strings.Builder
+			for i++; i < len(src) && rune(src[i]) != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				text.WriteByte(src[i])
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrSyntax, start)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, text.String(), start})
+
+		case isIdentStart(src[i]):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, src[start:i], start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokenOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, string(c), i)
+			}
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of expression", len(src)}), nil
+}
+
+// isIdentStart reports whether b can start a field or function name (ASCII, like field names)
+func isIdentStart(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// Parser, one method per precedence level from loosest to tightest:
+// ?: then || then && then comparisons then + - then * / % then unary ! -
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		return fmt.Errorf("%w: expected %q but found %q at position %d", ErrSyntax, op, tok.text, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, fmt.Errorf("%w: expression is nested too deeply", ErrSyntax)
+	}
+
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return conditional{cond, then, otherwise}, nil
+}
+
+// binaryLevels lists binary operators by precedence, loosest first
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op, left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.accept("-", "!"); ok {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, fmt.Errorf("%w: expression is nested too deeply", ErrSyntax)
+		}
+
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op, x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q at position %d", ErrSyntax, tok.text, tok.pos)
+		}
+		return literal{n}, nil
+
+	case tokenString:
+		return literal{tok.text}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		if _, ok := p.accept("("); !ok {
+			return ref{tok.text}, nil
+		}
+		if _, ok := functions[tok.text]; !ok && tok.text != "if" {
+			return nil, fmt.Errorf("%w: unknown function %q at position %d", ErrSyntax, tok.text, tok.pos)
+		}
+		var args []node
+		if _, ok := p.accept(")"); !ok {
+			for {
+				arg, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if _, ok := p.accept(","); !ok {
+					break
+				}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+		}
+		if tok.text == "if" {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("%w: if() takes 3 arguments at position %d", ErrSyntax, tok.pos)
+			}
+			return conditional{args[0], args[1], args[2]}, nil
+		}
+		return call{tok.text, args}, nil
+
+	case tokenOp:
+		if tok.text == "(" {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, tok.text, tok.pos)
+}
@@ -0,0 +1,104 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answers is a Lookup over a fixed map
+func answers(m map[string]string) Lookup {
+	return func(field string) (string, error) {
+		v, ok := m[field]
+		if !ok {
+			return "", fmt.Errorf("%s has no answer yet", field)
+		}
+		return v, nil
+	}
+}
+
+// TestEval tests arithmetic, date math, concatenation and conditionals
+func TestEval(t *testing.T) {
+	lookup := answers(map[string]string{
+		"valuation_cap":   "$5,000,000",
+		"purchase_amount": "500,000",
+		"start_date":      "2024-01-31",
+		"term_months":     "12",
+		"first_name":      "Jane",
+		"last_name":       "Doe",
+		"has_discount":    "Yes",
+		"discount_rate":   "20",
+	})
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"valuation_cap + purchase_amount", "5500000"},
+		{"format_number(valuation_cap + purchase_amount)", "5,500,000"},
+		{"(1 + 2) * 3 - 4 / 2", "7"},
+		{"0.1 + 0.2", "0.3"},
+		{"add_months(start_date, term_months)", "January 31, 2025"},
+		{"format_date(add_days(start_date, 1), \"01/02/2006\")", "02/01/2024"},
+		{"days_between(start_date, \"2024-03-01\")", "30"},
+		{"first_name + ' ' + last_name", "Jane Doe"},
+		{"concat(term_months, \" months\")", "12 months"},
+		{"has_discount ? discount_rate + \"%\" : \"N/A\"", "20%"},
+		{"if(has_discount == \"no\", 0, 100 - discount_rate)", "80"},
+		{"purchase_amount > 100000 && !false", "Yes"},
+		{"max(1, term_months, 3)", "12"},
+		{"round(10 / 3, 2)", "3.33"},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		got, err := e.Eval(lookup)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, got, tt.expr)
+	}
+}
+
+// TestParseErrors tests that malformed or oversized expressions are rejected
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"1 +",
+		"(1 + 2",
+		"\"unterminated",
+		"system(\"rm -rf /\")",
+		"a ? b",
+		"1 # 2",
+		strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100),
+		strings.Repeat("1+", 600) + "1",
+	} {
+		_, err := Parse(src)
+		assert.True(t, errors.Is(err, ErrSyntax), src)
+	}
+}
+
+// TestEvalErrors tests runtime errors such as missing answers and bad conversions
+func TestEvalErrors(t *testing.T) {
+	lookup := answers(map[string]string{"amount": "ten", "zero": "0", "long": strings.Repeat("x", 6000)})
+
+	for _, src := range []string{
+		"amount * 2", "1 / zero", "missing + 1", "add_days(amount, 1)",
+		"format_number(1, 300000000)", "format_number(1, -1)", "round(1, 11)", "round(1, 1.5)",
+		"long + long", "concat(long, long)",
+	} {
+		e, err := Parse(src)
+		require.NoError(t, err, src)
+		_, err = e.Eval(lookup)
+		assert.Error(t, err, src)
+	}
+}
+
+// TestFields tests that referenced field names are reported once each
+func TestFields(t *testing.T) {
+	e, err := Parse("a + b * add_months(c, a)")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, e.Fields())
+}
@@ -0,0 +1,223 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// functions available to expressions (if() is handled by the parser since it is lazy)
+var functions = map[string]func(args []value) (value, error){
+	"add_days":      dateArith(func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }),
+	"add_months":    dateArith(func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }),
+	"add_years":     dateArith(func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }),
+	"days_between":  daysBetween,
+	"format_date":   formatDate,
+	"round":         round,
+	"format_number": formatNumber,
+	"min":           extreme(-1),
+	"max":           extreme(1),
+	"abs":           abs,
+	"upper":         stringFunc(strings.ToUpper),
+	"lower":         stringFunc(strings.ToLower),
+	"trim":          stringFunc(strings.TrimSpace),
+	"concat":        concat,
+	"coalesce":      coalesce,
+}
+
+// maxDecimals caps round() and format_number() precision so one stored expression
+// can't build huge strings on every evaluation
+const maxDecimals = 10
+
+// maxTextLength caps text built by "+" and concat() for the same reason
+const maxTextLength = 10000
+
+// joinText joins formatted values, failing once the result is longer than maxTextLength
+func joinText(values ...value) (value, error) {
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString(format(v))
+		if b.Len() > maxTextLength {
+			return nil, fmt.Errorf("%w: text is longer than %d characters", ErrEval, maxTextLength)
+		}
+	}
+	return b.String(), nil
+}
+
+func arity(args []value, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("%w: takes %d arguments, got %d", ErrEval, min, len(args))
+		}
+		return fmt.Errorf("%w: takes %d to %d arguments, got %d", ErrEval, min, max, len(args))
+	}
+	return nil
+}
+
+// dateArith builds add_days/add_months/add_years(date, n)
+func dateArith(add func(time.Time, int) time.Time) func([]value) (value, error) {
+	return func(args []value) (value, error) {
+		if err := arity(args, 2, 2); err != nil {
+			return nil, err
+		}
+		t, err := toDate(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := toNumber(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return add(t, int(n)), nil
+	}
+}
+
+// daysBetween(from, to) is the number of days from the first date to the second
+func daysBetween(args []value) (value, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	from, err := toDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := toDate(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return math.Round(to.Sub(from).Hours() / 24), nil
+}
+
+// formatDate(date, layout) uses Go's reference date, e.g. "01/02/2006" or "2 January 2006"
+func formatDate(args []value) (value, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	t, err := toDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(format(args[1])), nil
+}
+
+// round(x) or round(x, digits)
+func round(args []value) (value, error) {
+	if err := arity(args, 1, 2); err != nil {
+		return nil, err
+	}
+	x, err := toNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+	digits, err := decimalsArg(args)
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, float64(digits))
+	return math.Round(x*scale) / scale, nil
+}
+
+// formatNumber(x) or formatNumber(x, decimals) adds thousands separators: 5500000 -> "5,500,000"
+func formatNumber(args []value) (value, error) {
+	if err := arity(args, 1, 2); err != nil {
+		return nil, err
+	}
+	x, err := toNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := decimalsArg(args)
+	if err != nil {
+		return nil, err
+	}
+
+	s := strconv.FormatFloat(math.Abs(x), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	if fraction != "" {
+		grouped.WriteString("." + fraction)
+	}
+	if x < 0 {
+		return "-" + grouped.String(), nil
+	}
+	return grouped.String(), nil
+}
+
+// decimalsArg reads the optional second argument of round/format_number: a whole
+// number from 0 to maxDecimals, default 0
+func decimalsArg(args []value) (int, error) {
+	if len(args) < 2 {
+		return 0, nil
+	}
+	n, err := toNumber(args[1])
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || n < 0 || n > maxDecimals {
+		return 0, fmt.Errorf("%w: decimals must be a whole number from 0 to %d, got %v", ErrEval, maxDecimals, n)
+	}
+	return int(n), nil
+}
+
+// extreme builds min (-1) and max (1) over one or more numbers
+func extreme(sign float64) func([]value) (value, error) {
+	return func(args []value) (value, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%w: takes at least 1 argument", ErrEval)
+		}
+		best, err := toNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args[1:] {
+			x, err := toNumber(arg)
+			if err != nil {
+				return nil, err
+			}
+			if (x-best)*sign > 0 {
+				best = x
+			}
+		}
+		return best, nil
+	}
+}
+
+func abs(args []value) (value, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	x, err := toNumber(args[0])
+	return math.Abs(x), err
+}
+
+func stringFunc(fn func(string) string) func([]value) (value, error) {
+	return func(args []value) (value, error) {
+		if err := arity(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return fn(format(args[0])), nil
+	}
+}
+
+// concat joins values as text, even numbers that "+" would add
+func concat(args []value) (value, error) {
+	return joinText(args...)
+}
+
+// coalesce returns the first non-blank value
+func coalesce(args []value) (value, error) {
+	for _, arg := range args {
+		if strings.TrimSpace(format(arg)) != "" {
+			return arg, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+)
+
+// Field describes a pending field offered to the extractor
+type Field struct {
+	Name     string
+	Type     string
+	Question string
+}
+
+// labelledValue matches "Label: value" or "Label = value"
+var labelledValue = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9 _\-]*?)\s*[:=]\s*(.+?)\s*$`)
+
+// FromMessage extracts values for any number of fields from a free-text message.
+// A nil client falls back to FromMessageWithRules.
+func FromMessage(client ai.Client, fields []Field, message string) ([]models.AnswerCandidate, error) {
+	if client == nil {
+		return FromMessageWithRules(fields, message), nil
+	}
+
+	prompt, err := prompts.Render(prompts.Extraction, struct {
+		Fields  []Field
+		Message string
+	}{fields, message})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ai.WithRedaction(client).Complete(ai.Request{System: prompt.System, Prompt: prompt.Prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []models.AnswerCandidate
+	if err := json.Unmarshal([]byte(ai.StripCodeFence(resp.Text)), &candidates); err != nil {
+		return nil, fmt.Errorf("failed to parse extracted answers: %w", err)
+	}
+
+	return filterCandidates(fields, candidates), nil
+}
+
+// FromMessageWithRules is the deterministic extractor used without an AI provider.
+// Lines like "Company Name: Acme Inc." are matched to fields by name; a message
+// without labels is taken as the answer to the first pending field, mirroring the
+// one-question-at-a-time flow.
+func FromMessageWithRules(fields []Field, message string) []models.AnswerCandidate {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	byKey := make(map[string]string, len(fields))
+	for _, f := range fields {
+		byKey[labelKey(f.Name)] = f.Name
+	}
+
+	var candidates []models.AnswerCandidate
+	for _, line := range strings.FieldsFunc(message, func(r rune) bool { return r == '\n' || r == ';' }) {
+		m := labelledValue.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if field, ok := byKey[labelKey(m[1])]; ok {
+			candidates = append(candidates, models.AnswerCandidate{Field: field, Value: m[2], Confidence: 1})
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = append(candidates, models.AnswerCandidate{
+			Field:      fields[0].Name,
+			Value:      strings.TrimSpace(message),
+			Confidence: 1,
+		})
+	}
+
+	return candidates
+}
+
+// filterCandidates drops values for unknown fields and empty values, keeping one per field
+func filterCandidates(fields []Field, candidates []models.AnswerCandidate) []models.AnswerCandidate {
+	pending := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		pending[f.Name] = true
+	}
+
+	seen := make(map[string]bool)
+	result := make([]models.AnswerCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		c.Value = strings.TrimSpace(c.Value)
+		if !pending[c.Field] || seen[c.Field] || c.Value == "" {
+			continue
+		}
+		seen[c.Field] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// labelKey normalizes "Company Name", "company-name" and "company_name" to the same key
+func labelKey(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(label)
+}
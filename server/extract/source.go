@@ -0,0 +1,99 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+)
+
+// maxSourceLength keeps source documents within token limits
+const maxSourceLength = 10000
+
+// FromSource proposes values for fields from a source document such as a term sheet.
+// Every proposal carries the snippet it came from; proposals whose snippet can't be
+// found in the source are dropped. A nil client falls back to FromSourceWithRules.
+func FromSource(client ai.Client, fields []Field, source string) ([]models.AnswerCandidate, error) {
+	if client == nil {
+		return FromSourceWithRules(fields, source), nil
+	}
+
+	truncated := source
+	if len(truncated) > maxSourceLength {
+		truncated = truncated[:maxSourceLength] + "... [truncated]"
+	}
+
+	prompt, err := prompts.Render(prompts.Prefill, struct {
+		Fields []Field
+		Source string
+	}{fields, truncated})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ai.WithRedaction(client).Complete(ai.Request{System: prompt.System, Prompt: prompt.Prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []models.AnswerCandidate
+	if err := json.Unmarshal([]byte(ai.StripCodeFence(resp.Text)), &candidates); err != nil {
+		return nil, fmt.Errorf("failed to parse proposed answers: %w", err)
+	}
+
+	grounded := make([]models.AnswerCandidate, 0, len(candidates))
+	for _, c := range filterCandidates(fields, candidates) {
+		if snippet := groundSnippet(source, c); snippet != "" {
+			c.Snippet = snippet
+			grounded = append(grounded, c)
+		}
+	}
+	return grounded, nil
+}
+
+// FromSourceWithRules is the deterministic extractor used without an AI provider.
+// It picks up "Label: value" lines, which is how most term sheets are laid out.
+func FromSourceWithRules(fields []Field, source string) []models.AnswerCandidate {
+	byKey := make(map[string]string, len(fields))
+	for _, f := range fields {
+		byKey[labelKey(f.Name)] = f.Name
+	}
+
+	var candidates []models.AnswerCandidate
+	for _, line := range strings.Split(source, "\n") {
+		m := labelledValue.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if field, ok := byKey[labelKey(m[1])]; ok {
+			candidates = append(candidates, models.AnswerCandidate{
+				Field:      field,
+				Value:      m[2],
+				Confidence: 1,
+				Snippet:    strings.TrimSpace(line),
+			})
+		}
+	}
+	return filterCandidates(fields, candidates)
+}
+
+// groundSnippet returns the snippet if it appears in source, otherwise the line
+// containing the value, or "" when the value can't be found at all
+func groundSnippet(source string, c models.AnswerCandidate) string {
+	lowerSource := strings.ToLower(source)
+	if c.Snippet != "" && strings.Contains(lowerSource, strings.ToLower(strings.TrimSpace(c.Snippet))) {
+		return strings.TrimSpace(c.Snippet)
+	}
+	if i := strings.Index(lowerSource, strings.ToLower(c.Value)); i >= 0 {
+		start := strings.LastIndex(source[:i], "\n") + 1
+		end := strings.Index(source[i:], "\n")
+		if end < 0 {
+			return strings.TrimSpace(source[start:])
+		}
+		return strings.TrimSpace(source[start : i+end])
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// roleKey is the context key holding the role a request acts as
+const roleKey = "role"
+
+// collaboratorRoutes are the session routes a collaborator's token opens; every
+// other session route needs the owner token
+var collaboratorRoutes = map[string]bool{
+	"GET /api/session/:id/next":              true,
+	"GET /api/session/:id/questions/:field":  true,
+	"POST /api/session/:id/answers":          true,
+	"DELETE /api/session/:id/answers/:field": true,
+	"POST /api/session/:id/skip":             true,
+	"GET /api/session/:id/events":            true,
+}
+
+// SessionAccess checks the token on session routes once collaborators are invited.
+// The token comes from the X-Session-Token header or a ?token= link parameter; the
+// role it grants is kept on the request for the handlers to scope fields by.
+func SessionAccess(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.FullPath(), "/api/session/:id") {
+			c.Next()
+			return
+		}
+
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			// Let the handler report the missing session
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader("X-Session-Token")
+		if token == "" {
+			token = c.Query("token")
+		}
+		role, ok := session.Authorize(sess, token)
+		if !ok {
+			code, message := "invalid_token", "This access link is not valid for this session."
+			if token == "" {
+				code, message = "token_required", "This session is shared; an access token is required."
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: code, Message: message})
+			return
+		}
+		if role != session.Owner && !collaboratorRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "owner_only",
+				Message: "Only the session owner can do this.",
+			})
+			return
+		}
+
+		c.Set(roleKey, role)
+		c.Next()
+	}
+}
+
+// AdminAccess guards server-wide settings, such as webhooks, with the admin token,
+// sent as "Authorization: Bearer <token>". With no token configured every request
+// is refused.
+func AdminAccess(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "admin_disabled",
+				Message: "Set ADMIN_TOKEN on the server to use this endpoint.",
+			})
+			return
+		}
+
+		given, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "admin_token_required",
+				Message: "A valid admin token is required.",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestRole returns the role the request acts as; routes outside SessionAccess act as the owner
+func requestRole(c *gin.Context) string {
+	if role := c.GetString(roleKey); role != "" {
+		return role
+	}
+	return session.Owner
+}
+
+// checkAssigned responds 403 and returns false when the request's role may not answer field
+func checkAssigned(c *gin.Context, sess *models.Session, field string) bool {
+	if session.CanAnswer(sess, requestRole(c), field) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, models.ErrorResponse{
+		Error:   "field_not_assigned",
+		Message: "Field '" + field + "' is answered by another party.",
+	})
+	return false
+}
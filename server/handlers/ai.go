@@ -1,44 +1,42 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"strings"
+	"slices"
 
 	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
 	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+	"github.com/you/lexsy-mvp/server/utils"
 )
 
-// OpenAI API structures
-type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
-}
-
-type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type openAIResponse struct {
-	Choices []struct {
-		Message openAIMessage `json:"message"`
-	} `json:"choices"`
-}
-
 // fieldMetadata contains AI-generated question and type for a field
 type fieldMetadata struct {
-	Question string `json:"question"`
-	Type     string `json:"type"` // "text", "number", or "date"
+	Question string   `json:"question"`
+	Type     string   `json:"type"`    // One of utils.FieldTypeNames
+	Options  []string `json:"options"` // Choices when Type is "select"
 }
 
-// HandleGenerateQuestions generates natural questions for all fields using OpenAI
-func HandleGenerateQuestions(store *session.Store) gin.HandlerFunc {
+// meteredClient returns the configured AI client wrapped for usage accounting,
+// or nil when no provider is configured
+func meteredClient(ledger *usage.Ledger, sessionID, template, operation string, preferred ...ai.Provider) ai.Client {
+	client, err := ai.FromEnv(preferred...)
+	if err != nil {
+		return nil
+	}
+	return ledger.Meter(client, sessionID, template, operation)
+}
+
+// HandleGenerateQuestions generates natural questions for all fields using the configured AI provider.
+// With ?stream=true each question is saved and sent as a Server-Sent Event as soon as
+// the provider produces it, so the interview can start before generation finishes.
+func HandleGenerateQuestions(store *session.Store, ledger *usage.Ledger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionID := c.Param("id")
 
@@ -52,32 +50,55 @@ func HandleGenerateQuestions(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Check for OpenAI API key
-		apiKey := os.Getenv("OPENAI_API_KEY")
-		if apiKey == "" {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "api_key_missing",
-				Message: "OpenAI API key not configured.",
+		// Generation takes a while and fields may be edited meanwhile, so work from a copy
+		var fields []string
+		store.View(sessionID, func(s *models.Session) {
+			fields = slices.Clone(s.Fields)
+		})
+
+		// Fall back to humanized questions when no AI provider is configured
+		client := meteredClient(ledger, sess.ID, sess.Template, "questions", ai.ProviderOpenAI, ai.ProviderGemini)
+		if c.Query("stream") == "true" {
+			streamQuestions(c, store, sessionID, fields, client)
+			return
+		}
+		if client == nil {
+			questions := make(map[string]string)
+			for _, field := range fields {
+				questions[field] = humanizeFieldName(field)
+			}
+
+			c.JSON(http.StatusOK, models.GenerateQuestionsResponse{
+				Count:     len(questions),
+				Questions: questions,
+				Provider:  string(ai.ProviderNone),
+				Message:   "No AI provider configured; using standard questions.",
 			})
 			return
 		}
 
 		// Generate questions and field types for all fields
-		fieldMetadataMap, err := generateQuestionsWithAI(sess.Fields, apiKey)
+		fieldMetadataMap, err := generateQuestionsWithAI(client, fields)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "ai_generation_failed",
-				Message: "Failed to generate questions with AI: " + err.Error(),
-			})
+			status, errResp := generationError(err)
+			c.JSON(status, errResp)
 			return
 		}
 
-		// Update session with AI-generated questions and field types
+		// Update session with AI-generated questions and field types. Fields the model
+		// made up, or that were removed meanwhile, are skipped as in the stream.
+		questions := make(map[string]string)
+		by := editor(c, "questions")
 		err = store.Update(sessionID, func(s *models.Session) {
+			s.PromptVersions[prompts.Questions] = prompts.Version(prompts.Questions)
 			for field, metadata := range fieldMetadataMap {
-				s.Questions[field] = metadata.Question
-				s.FieldTypes[field] = metadata.Type
+				if !session.HasField(s, field) {
+					continue
+				}
+				applyFieldMetadata(s, field, metadata, by)
+				questions[field] = metadata.Question
 			}
+			session.Notify(s, models.SessionEvent{Type: session.EventQuestions, Count: len(questions)})
 		})
 
 		if err != nil {
@@ -88,83 +109,57 @@ func HandleGenerateQuestions(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Extract just questions for the response
-		questions := make(map[string]string)
-		for field, metadata := range fieldMetadataMap {
-			questions[field] = metadata.Question
-		}
-
 		c.JSON(http.StatusOK, models.GenerateQuestionsResponse{
 			Count:     len(questions),
 			Questions: questions,
+			Provider:  string(client.Provider()),
 			Message:   "AI questions and field types generated successfully.",
 		})
 	}
 }
 
-// generateQuestionsWithAI calls OpenAI API to generate natural questions and field types
-func generateQuestionsWithAI(fields []string, apiKey string) (map[string]fieldMetadata, error) {
-	// Build prompt
-	prompt := buildPrompt(fields)
-
-	// Prepare OpenAI request
-	reqBody := openAIRequest{
-		Model: "gpt-4",
-		Messages: []openAIMessage{
-			{
-				Role:    "system",
-				Content: "You are a helpful legal assistant that converts technical field names into natural, conversational questions and determines appropriate input types. Always respond with valid JSON only.",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+// applyFieldMetadata saves an AI-phrased question and the type chosen with it. An unknown
+// type (or a select without options) leaves the inferred type in place; agreeing with
+// the inference keeps its reason. A saved answer that doesn't fit the new type is
+// cleared on behalf of by.
+func applyFieldMetadata(s *models.Session, field string, metadata fieldMetadata, by session.Editor) {
+	s.Questions[field] = metadata.Question
+	if metadata.Type != s.FieldTypes[field] {
+		session.SetFieldType(s, field, metadata.Type, metadata.Options, "chosen by AI while phrasing questions", by)
+	} else if len(metadata.Options) > 0 {
+		session.SetFieldType(s, field, metadata.Type, metadata.Options, s.FieldTypeReasons[field], by)
 	}
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// generationError maps a question generation failure to an HTTP status and error body
+func generationError(err error) (int, models.ErrorResponse) {
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		return http.StatusPaymentRequired, models.ErrorResponse{
+			Error:   "budget_exceeded",
+			Message: err.Error(),
+		}
 	}
-
-	// Make API request
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	return http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "ai_generation_failed",
+		Message: "Failed to generate questions with AI: " + err.Error(),
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// generateQuestionsWithAI calls the configured provider to generate natural questions and field types
+func generateQuestionsWithAI(client ai.Client, fields []string) (map[string]fieldMetadata, error) {
+	// Build prompt
+	prompt, err := buildPrompt(fields)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	resp, err := client.Complete(ai.Request{System: prompt.System, Prompt: prompt.Prompt})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// Parse OpenAI response
-	var openAIResp openAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
-	}
-
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from OpenAI")
+		return nil, err
 	}
 
 	// Parse the JSON content from AI
-	content := openAIResp.Choices[0].Message.Content
+	content := ai.StripCodeFence(resp.Text)
 	var fieldMetadataMap map[string]fieldMetadata
 	if err := json.Unmarshal([]byte(content), &fieldMetadataMap); err != nil {
 		return nil, fmt.Errorf("failed to parse AI-generated field metadata: %w", err)
@@ -173,30 +168,7 @@ func generateQuestionsWithAI(fields []string, apiKey string) (map[string]fieldMe
 	return fieldMetadataMap, nil
 }
 
-// buildPrompt creates the prompt for OpenAI
-func buildPrompt(fields []string) string {
-	fieldList := strings.Join(fields, "\n- ")
-
-	return fmt.Sprintf(`I have a legal document with the following placeholder fields:
-- %s
-
-For each field, please:
-1. Convert the field name into a natural, conversational question that I can ask a client
-2. Determine the appropriate input type: "text", "number", or "date"
-
-The questions should be friendly, professional, and easy to understand.
-
-Return ONLY a JSON object where keys are the field names and values are objects with "question" and "type" properties.
-Example format:
-{
-  "client_name": {"question": "What is the client's full name?", "type": "text"},
-  "effective_date": {"question": "When should this agreement take effect?", "type": "date"},
-  "contract_amount": {"question": "What is the total contract amount?", "type": "number"}
-}
-
-Use "date" for any date-related fields (dates, birthdays, deadlines, etc.)
-Use "number" for numeric values (amounts, ages, quantities, counts, etc.)
-Use "text" for everything else (names, descriptions, addresses, etc.)
-
-Do not include any explanation, just the JSON object.`, fieldList)
+// buildPrompt renders the question generation prompt template for the fields
+func buildPrompt(fields []string) (prompts.Rendered, error) {
+	return prompts.Render(prompts.Questions, struct{ Fields, Types []string }{fields, utils.FieldTypeNames})
 }
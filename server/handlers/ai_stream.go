@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// streamedField is one field's metadata parsed from a partial AI answer
+type streamedField struct {
+	Field    string
+	Metadata fieldMetadata
+}
+
+// streamQuestions sends a "question" event with each of fields' questions as soon as
+// it is produced, saving it first, then a "done" event with the full set. Errors before
+// anything is sent get a normal JSON response; later ones an "error" event. The session
+// is only read under the store lock, since answers and edits continue meanwhile.
+func streamQuestions(c *gin.Context, store *session.Store, sessionID string, fields []string, client ai.Client) {
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	send := func(event string, data any) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	questions := make(map[string]string)
+	if client == nil {
+		for _, field := range fields {
+			var question models.QuestionResponse
+			known := false
+			store.View(sessionID, func(s *models.Session) {
+				if known = session.HasField(s, field); known {
+					question = questionFor(s, field)
+				}
+			})
+			if known {
+				questions[field] = humanizeFieldName(field)
+				send("question", question)
+			}
+		}
+		send("done", models.GenerateQuestionsResponse{
+			Count:     len(questions),
+			Questions: questions,
+			Provider:  string(ai.ProviderNone),
+			Message:   "No AI provider configured; using standard questions.",
+		})
+		return
+	}
+
+	fail := func(err error) {
+		status, errResp := generationError(err)
+		if c.Writer.Written() {
+			send("error", errResp)
+			return
+		}
+		c.JSON(status, errResp)
+	}
+
+	prompt, err := buildPrompt(fields)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// Each time more text arrives, save and send the fields that are now complete
+	resp, err := ai.Stream(client, ai.Request{System: prompt.System, Prompt: prompt.Prompt}, func(text string) {
+		for _, parsed := range completedFields(text) {
+			if _, sent := questions[parsed.Field]; sent {
+				continue
+			}
+			questions[parsed.Field] = parsed.Metadata.Question
+
+			var question models.QuestionResponse
+			known := false
+			store.Update(sessionID, func(s *models.Session) {
+				if known = session.HasField(s, parsed.Field); known {
+					s.PromptVersions[prompts.Questions] = prompts.Version(prompts.Questions)
+					applyFieldMetadata(s, parsed.Field, parsed.Metadata, editor(c, "questions"))
+					question = questionFor(s, parsed.Field)
+				}
+			})
+			if known {
+				send("question", question)
+			}
+		}
+	})
+	if err == nil && len(questions) == 0 {
+		err = fmt.Errorf("failed to parse AI-generated field metadata from %q", ai.StripCodeFence(resp.Text))
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	store.Update(sessionID, func(s *models.Session) {
+		session.Notify(s, models.SessionEvent{Type: session.EventQuestions, Count: len(questions)})
+	})
+	send("done", models.GenerateQuestionsResponse{
+		Count:     len(questions),
+		Questions: questions,
+		Provider:  string(client.Provider()),
+		Message:   "AI questions and field types generated successfully.",
+	})
+}
+
+// completedFields parses the fields whose metadata has fully arrived in a partial
+// JSON object, in the order they appear
+func completedFields(text string) []streamedField {
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text[start:]))
+	if _, err := decoder.Token(); err != nil {
+		return nil
+	}
+	var fields []streamedField
+	for decoder.More() {
+		token, err := decoder.Token()
+		field, ok := token.(string)
+		if err != nil || !ok {
+			break
+		}
+		var metadata fieldMetadata
+		if err := decoder.Decode(&metadata); err != nil {
+			break
+		}
+		fields = append(fields, streamedField{Field: field, Metadata: metadata})
+	}
+	return fields
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleSetCollaborator assigns fields to a role (e.g. company counsel or investor) and
+// returns the role's access token and interview link. Inviting the first collaborator
+// locks the session: from then on the owner token is needed for everything else.
+func HandleSetCollaborator(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CollaboratorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: role, fields",
+			})
+			return
+		}
+
+		sessionID := c.Param("id")
+		var collaborator models.Collaborator
+		var setErr error
+		err := store.Update(sessionID, func(s *models.Session) {
+			collaborator, setErr = session.SetCollaborator(s, docx.NormalizeFieldName(req.Role), req.Fields)
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+		if setErr != nil {
+			status, code := http.StatusInternalServerError, "update_failed"
+			if errors.Is(setErr, session.ErrInvalidCollaborator) {
+				status, code = http.StatusBadRequest, "invalid_collaborator"
+			}
+			c.JSON(status, models.ErrorResponse{Error: code, Message: setErr.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CollaboratorResponse{
+			Role:   collaborator.Role,
+			Fields: collaborator.Fields,
+			Token:  collaborator.Token,
+			Link:   "/chat/" + sessionID + "?token=" + url.QueryEscape(collaborator.Token),
+		})
+	}
+}
+
+// HandleListCollaborators shows the owner each role's progress and the overall progress
+func HandleListCollaborators(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		collaborators, unassigned := session.Progress(sess)
+		progress, total := session.Completion(sess)
+		c.JSON(http.StatusOK, models.CollaboratorsResponse{
+			Collaborators: collaborators,
+			Unassigned:    unassigned,
+			Progress:      progress,
+			Total:         total,
+			IsCompleted:   progress == total,
+		})
+	}
+}
+
+// HandleRemoveCollaborator revokes a role's token; its fields go back to the owner
+func HandleRemoveCollaborator(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := docx.NormalizeFieldName(c.Param("role"))
+		found := false
+		err := store.Update(c.Param("id"), func(s *models.Session) {
+			_, found = s.Collaborators[role]
+			delete(s.Collaborators, role)
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "collaborator_not_found",
+				Message: "No collaborator with role '" + role + "'.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed.", "role": role})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// eventKeepAlive is how often an idle stream sends a comment so proxies keep it open
+const eventKeepAlive = 15 * time.Second
+
+// HandleSessionEvents streams a session's changes as Server-Sent Events: answers set
+// or cleared, AI questions generated and document generation status. A collaborator
+// only sees answers to their own fields.
+func HandleSessionEvents(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+		events, stop, err := store.Watch(sessionID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+		defer stop()
+
+		// The session keeps changing while the stream is open, so read it under the lock
+		role := requestRole(c)
+		var progress, total int
+		store.View(sessionID, func(s *models.Session) {
+			progress, total = session.CompletionAmong(s, session.AssignedFields(s, role))
+		})
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+		c.SSEvent(session.EventReady, models.SessionEvent{
+			Type:     session.EventReady,
+			Progress: progress,
+			Total:    total,
+			At:       time.Now(),
+		})
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				if event.Type == session.EventAnswer && !canSee(store, sessionID, role, event.Field) {
+					return true
+				}
+				c.SSEvent(event.Type, event)
+				return true
+			case <-keepAlive.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// canSee reports whether a watcher with role may see answers to field
+func canSee(store *session.Store, sessionID, role, field string) bool {
+	allowed := false
+	store.View(sessionID, func(s *models.Session) {
+		allowed = session.CanAnswer(s, role, field)
+	})
+	return allowed
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/profiles"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleAddField adds a field the detector missed, optionally with its exact placeholder text
+func HandleAddField(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AddFieldRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: field",
+			})
+			return
+		}
+
+		field := docx.NormalizeFieldName(req.Field)
+		if field == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_field",
+				Message: "Field name must contain letters or digits.",
+			})
+			return
+		}
+
+		handleFieldChange(c, store, func(s *models.Session) error {
+			if req.Type != "" {
+				return session.AddField(s, field, req.Placeholder, req.Type, req.Options, typeSetByHand)
+			}
+			profile, err := profiles.Get(s.DocumentType)
+			if err != nil {
+				profile, _ = profiles.Get(profiles.Generic)
+			}
+			inferred := profile.ExplainFieldType(field, "", "")
+			return session.AddField(s, field, req.Placeholder, inferred.Type, req.Options, inferred.Reason)
+		})
+	}
+}
+
+// typeSetByHand is the type reason for fields whose type was chosen through the API
+const typeSetByHand = "set by hand"
+
+// HandleRemoveField removes a falsely detected field and its answer
+func HandleRemoveField(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handleFieldChange(c, store, func(s *models.Session) error {
+			return session.RemoveField(s, c.Param("field"), editor(c, "edit"))
+		})
+	}
+}
+
+// HandleUpdateField renames a field and/or changes its type or format
+func HandleUpdateField(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.UpdateFieldRequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Name == "" && req.Type == "" && len(req.Options) == 0 && req.Format == "") {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: name, type, options and/or format",
+			})
+			return
+		}
+
+		handleFieldChange(c, store, func(s *models.Session) error {
+			field := c.Param("field")
+
+			if !session.HasField(s, field) {
+				return fmt.Errorf("%w: %s", session.ErrFieldNotFound, field)
+			}
+
+			// Check the whole edit before changing anything, so a bad type doesn't leave a rename behind
+			fieldType, reason := req.Type, typeSetByHand
+			if fieldType == "" {
+				fieldType, reason = s.FieldTypes[field], s.FieldTypeReasons[field]
+			}
+			retype := req.Type != "" || len(req.Options) > 0
+			if retype {
+				if err := session.CheckFieldType(s, field, fieldType, req.Options); err != nil {
+					return err
+				}
+			}
+			if req.Format != "" {
+				if err := session.CheckFieldFormat(fieldType, req.Format); err != nil {
+					return err
+				}
+			}
+
+			if req.Name != "" {
+				newName := docx.NormalizeFieldName(req.Name)
+				if newName != field {
+					if err := session.RenameField(s, field, newName, editor(c, "edit")); err != nil {
+						return err
+					}
+					field = newName
+				}
+			}
+			if retype {
+				if err := session.SetFieldType(s, field, fieldType, req.Options, reason, editor(c, "edit")); err != nil {
+					return err
+				}
+			}
+			if req.Format != "" {
+				return session.SetFieldFormat(s, field, req.Format)
+			}
+			return nil
+		})
+	}
+}
+
+// handleFieldChange applies a change to the session's field list and responds with the
+// updated fields, types and aliases
+func handleFieldChange(c *gin.Context, store *session.Store, change func(s *models.Session) error) {
+	sessionID := c.Param("id")
+	if _, err := store.Get(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "session_not_found",
+			Message: "Session not found.",
+		})
+		return
+	}
+
+	var changeErr error
+	response := gin.H{}
+	err := store.Update(sessionID, func(s *models.Session) {
+		if changeErr = change(s); changeErr == nil {
+			response["fields"] = s.Fields
+			response["fieldTypes"] = s.FieldTypes
+			response["aliases"] = s.Aliases
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update session.",
+		})
+		return
+	}
+	if changeErr != nil {
+		status, code := http.StatusBadRequest, "invalid_field_change"
+		switch {
+		case errors.Is(changeErr, session.ErrInvalidMerge):
+			code = "invalid_merge"
+		case errors.Is(changeErr, session.ErrFieldNotFound):
+			status, code = http.StatusNotFound, "invalid_field"
+		case errors.Is(changeErr, session.ErrFieldExists):
+			status, code = http.StatusConflict, "field_exists"
+		case errors.Is(changeErr, session.ErrFieldInUse):
+			status, code = http.StatusConflict, "field_in_use"
+		case errors.Is(changeErr, session.ErrInvalidType):
+			code = "invalid_type"
+		case errors.Is(changeErr, session.ErrInvalidFormat):
+			code = "invalid_format"
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   code,
+			Message: changeErr.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
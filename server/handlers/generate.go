@@ -2,21 +2,36 @@ package handlers
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/jobs"
 	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
 )
 
-// HandleGenerateDocument generates the filled document for download
-func HandleGenerateDocument(store *session.Store) gin.HandlerFunc {
+// HandleGenerateDocument generates the filled document for download, or a ZIP of
+// every filled document for a package. With ?async=true the document is filled in a
+// background job and downloaded from the job once it finishes.
+func HandleGenerateDocument(store *session.Store, ledger *usage.Ledger, queue *jobs.Queue) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionID := c.Param("id")
 
-		// Get session
-		sess, err := store.Get(sessionID)
+		// Copy what filling needs under the lock: the job may run while the session
+		// keeps changing
+		var unansweredFields []string
+		var input generation
+		var fillErr error
+		err := store.View(sessionID, func(s *models.Session) {
+			// Check if all required fields have been answered
+			if unansweredFields = session.MissingRequired(s); len(unansweredFields) == 0 {
+				input, fillErr = newGeneration(s)
+			}
+		})
 		if err != nil {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error:   "session_not_found",
@@ -24,15 +39,6 @@ func HandleGenerateDocument(store *session.Store) gin.HandlerFunc {
 			})
 			return
 		}
-
-		// Check if all fields have been answered
-		unansweredFields := []string{}
-		for _, field := range sess.Fields {
-			if _, answered := sess.Answers[field]; !answered {
-				unansweredFields = append(unansweredFields, field)
-			}
-		}
-
 		if len(unansweredFields) > 0 {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error:   "incomplete_answers",
@@ -40,20 +46,99 @@ func HandleGenerateDocument(store *session.Store) gin.HandlerFunc {
 			})
 			return
 		}
-
-		// Fill the document with answers
-		filledDoc, err := docx.FillDocument(sess.OriginalDoc, sess.Answers)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "document_generation_failed",
-				Message: "Failed to generate document: " + err.Error(),
+		if fillErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "computed_field_failed",
+				Message: fillErr.Error(),
 			})
 			return
 		}
 
-		// Return the document as a downloadable file
-		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
-		c.Header("Content-Disposition", "attachment; filename=filled_document.docx")
-		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", filledDoc)
+		runOrQueue(c, queue, "generate", sessionID, func(report func(int, string)) jobs.Outcome {
+			// Fill the document with answers; a package fills every document into one ZIP
+			report(10, "filling document")
+			notifyGeneration(store, sessionID, session.GenerationStarted)
+			client := meteredClient(ledger, sessionID, input.template, "map", docx.DetectionProviders...)
+			var filledDoc []byte
+			var err error
+			if len(input.documents) > 0 {
+				filledDoc, err = fillPackage(input.documents, input.placeholders, client)
+			} else {
+				filledDoc, err = docx.FillDocument(input.doc, input.values, input.placeholders, client)
+			}
+			if err != nil {
+				notifyGeneration(store, sessionID, session.GenerationFailed)
+				return jobs.Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+					Error:   "document_generation_failed",
+					Message: "Failed to generate document: " + err.Error(),
+				}}
+			}
+
+			store.Update(sessionID, func(s *models.Session) {
+				if client != nil {
+					s.PromptVersions[prompts.Mapping] = prompts.Version(prompts.Mapping)
+				}
+				session.Notify(s, models.SessionEvent{Type: session.EventGeneration, Status: session.GenerationCompleted})
+			})
+
+			if len(input.documents) > 0 {
+				return jobs.Outcome{Status: http.StatusOK, File: filledDoc, FileName: "filled_documents.zip", ContentType: "application/zip"}
+			}
+
+			// Return the document as a downloadable file
+			return jobs.Outcome{
+				Status:      http.StatusOK,
+				File:        filledDoc,
+				FileName:    "filled_document.docx",
+				ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			}
+		})
+	}
+}
+
+// generation is a copy of everything needed to fill a session's documents
+type generation struct {
+	template     string
+	doc          []byte            // The template, for single-document sessions
+	values       map[string]string // Fill values for every field
+	documents    []packageDocument // Each document of a package with its own values
+	placeholders map[string]string
+}
+
+// packageDocument is one document of a package and the values to fill it with
+type packageDocument struct {
+	name   string
+	doc    []byte
+	values map[string]string
+}
+
+// newGeneration copies what filling needs from the session. Computed fields are
+// calculated; hidden fields and unanswered optional fields are filled in blank.
+func newGeneration(s *models.Session) (generation, error) {
+	values, err := session.FillValues(s)
+	if err != nil {
+		return generation{}, err
+	}
+
+	input := generation{
+		template:     s.Template,
+		doc:          s.OriginalDoc,
+		values:       values,
+		placeholders: maps.Clone(s.Placeholders),
 	}
+	for _, doc := range s.Documents {
+		input.documents = append(input.documents, packageDocument{
+			name:   doc.Name,
+			doc:    doc.OriginalDoc,
+			values: session.DocumentValues(s, values, doc),
+		})
+	}
+	return input, nil
+}
+
+// notifyGeneration tells the session's watchers how document generation is going
+func notifyGeneration(store *session.Store, sessionID, status string) {
+	store.Update(sessionID, func(s *models.Session) {
+		session.Notify(s, models.SessionEvent{Type: session.EventGeneration, Status: status})
+	})
 }
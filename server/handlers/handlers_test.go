@@ -1,25 +1,45 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/jobs"
 	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/parties"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+	"github.com/you/lexsy-mvp/server/webhooks"
 )
 
+// testAdminToken is the admin token the test router accepts
+const testAdminToken = "test-admin-token"
+
 // setupTestRouter creates a test router with the same routes as the main application
 func setupTestRouter() (*gin.Engine, *session.Store) {
 	gin.SetMode(gin.TestMode)
 	store := session.NewStore()
+	ledger := usage.NewLedger()
+	directory := parties.NewDirectory()
+	queue := jobs.NewQueue()
+	dispatcher := webhooks.NewDispatcher()
+	store.Listen(dispatcher.SessionListener)
 	
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
@@ -31,12 +51,63 @@ func setupTestRouter() (*gin.Engine, *session.Store) {
 	
 	// API routes
 	api := r.Group("/api")
+	api.Use(SessionAccess(store))
 	{
-		api.POST("/upload", HandleUpload(store))
-		api.GET("/session/:id", HandleGetSession(store))
+		api.POST("/upload", HandleUpload(store, ledger, queue))
+		api.POST("/reverse", HandleReverseExtract(store, ledger, queue))
+		api.POST("/packages", HandleUploadPackage(store, ledger, queue))
+		api.GET("/session/:id", HandleGetSession(store, ledger))
 		api.POST("/session/:id/answers", HandleSubmitAnswers(store))
 		api.GET("/session/:id/next", HandleGetNextQuestion(store))
-		api.POST("/session/:id/generate", HandleGenerateDocument(store))
+		api.DELETE("/session/:id/answers/:field", HandleClearAnswer(store))
+		api.POST("/session/:id/skip", HandleSkipField(store))
+		api.POST("/session/:id/fields", HandleAddField(store))
+		api.PATCH("/session/:id/fields/:field", HandleUpdateField(store))
+		api.DELETE("/session/:id/fields/:field", HandleRemoveField(store))
+		api.PUT("/session/:id/fields/:field/optional", HandleSetOptional(store))
+		api.GET("/session/:id/questions/:field", HandleGetQuestion(store))
+		api.PUT("/session/:id/fields/:field/condition", HandleSetCondition(store))
+		api.DELETE("/session/:id/fields/:field/condition", HandleDeleteCondition(store))
+		api.PUT("/session/:id/fields/:field/computed", HandleSetComputed(store))
+		api.DELETE("/session/:id/fields/:field/computed", HandleDeleteComputed(store))
+		api.GET("/session/:id/merges", HandleGetMergeSuggestions(store))
+		api.POST("/session/:id/merge", HandleMergeFields(store))
+		api.POST("/session/:id/split", HandleSplitField(store))
+		api.PUT("/session/:id/roles/:role", HandleBindRole(store, directory))
+		api.DELETE("/session/:id/roles/:role", HandleUnbindRole(store))
+		api.POST("/session/:id/collaborators", HandleSetCollaborator(store))
+		api.GET("/session/:id/collaborators", HandleListCollaborators(store))
+		api.DELETE("/session/:id/collaborators/:role", HandleRemoveCollaborator(store))
+		api.GET("/parties", HandleListParties(directory))
+		api.POST("/parties", HandleCreateParty(directory))
+		api.GET("/parties/:id", HandleGetParty(directory))
+		api.PUT("/parties/:id", HandleUpdateParty(directory))
+		api.DELETE("/parties/:id", HandleDeleteParty(directory))
+		api.GET("/session/:id/history", HandleGetHistory(store))
+		api.GET("/session/:id/events", HandleSessionEvents(store))
+		api.POST("/session/:id/undo", HandleUndo(store))
+		api.POST("/session/:id/revert", HandleRevert(store))
+		api.POST("/session/:id/message", HandleMessage(store, ledger))
+		api.POST("/session/:id/prefill", HandlePrefill(store, ledger))
+		api.GET("/session/:id/prefill", HandleGetPrefill(store))
+		api.POST("/session/:id/prefill/review", HandleReviewPrefill(store))
+		api.POST("/session/:id/ai/questions", HandleGenerateQuestions(store, ledger))
+		api.PUT("/session/:id/budget", HandleSetBudget(store, ledger))
+		api.POST("/session/:id/generate", HandleGenerateDocument(store, ledger, queue))
+		api.GET("/jobs/:id", HandleGetJob(queue))
+		api.GET("/jobs/:id/download", HandleDownloadJob(queue))
+		adminAccess := AdminAccess(testAdminToken)
+		admin := api.Group("/webhooks", adminAccess)
+		admin.GET("", HandleListWebhooks(dispatcher))
+		admin.POST("", HandleCreateWebhook(dispatcher))
+		admin.GET("/:id", HandleGetWebhook(dispatcher))
+		admin.PUT("/:id", HandleUpdateWebhook(dispatcher))
+		admin.DELETE("/:id", HandleDeleteWebhook(dispatcher))
+		admin.GET("/:id/deliveries", HandleListDeliveries(dispatcher))
+		admin.POST("/:id/test", HandleTestWebhook(dispatcher))
+		api.GET("/usage", HandleGetUsage(ledger))
+		api.GET("/prompts", HandleListPrompts())
+		api.POST("/prompts/reload", adminAccess, HandleReloadPrompts())
 	}
 	
 	return r, store
@@ -201,3 +272,1307 @@ func TestSessionWorkflow(t *testing.T) {
 	assert.Equal(t, 2, questionResponse.Progress)
 	assert.Equal(t, 2, questionResponse.Total)
 }
+
+// TestGenerateQuestionsWithoutProvider tests that question generation falls back to humanized questions
+func TestGenerateQuestionsWithoutProvider(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/ai/questions", sess.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.GenerateQuestionsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, "none", response.Provider)
+	assert.Equal(t, "What is the Company Name?", response.Questions["company_name"])
+
+	// Fallback questions are not stored, so the interview still reports them as auto-generated
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/next", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var questionResponse models.QuestionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &questionResponse)
+	require.NoError(t, err)
+	assert.False(t, questionResponse.IsAIPhrased)
+}
+
+// TestMessageExtractsLabelledAnswers tests free-text answers without an AI provider
+func TestMessageExtractsLabelledAnswers(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "purchase_amount", "investor_name", "notes"})
+	require.NoError(t, err)
+	sess.Optional["notes"] = true
+
+	body := []byte(`{"message": "Company Name: Acme Inc.\npurchase amount = 500000"}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/message", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.MessageResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Len(t, response.Saved, 2)
+	assert.Empty(t, response.FollowUps)
+	assert.Equal(t, 2, response.Progress)
+	assert.False(t, response.Done)
+
+	saved, _ := store.Get(sess.ID)
+	assert.Equal(t, "Acme Inc.", saved.Answers["company_name"])
+	assert.Equal(t, "500000", saved.Answers["purchase_amount"])
+
+	// An unlabelled reply answers the next pending field
+	body = []byte(`{"message": "Jane Investor"}`)
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/message", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	// Done once every required field is answered, even with the optional one left
+	assert.True(t, response.Done)
+	assert.Equal(t, 3, response.Progress)
+	assert.Equal(t, 3, response.Total) // The optional field isn't counted
+	assert.Equal(t, "Jane Investor", saved.Answers["investor_name"])
+}
+
+// TestMessageConcurrentFieldEdits tests that messages are checked against the session
+// safely while its field types change (run with -race)
+func TestMessageConcurrentFieldEdits(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "purchase_amount"})
+	require.NoError(t, err)
+
+	// Keep switching the amount's type while messages arrive
+	started := make(chan struct{})
+	done := make(chan struct{})
+	churned := make(chan struct{})
+	go func() {
+		defer close(churned)
+		by := session.Editor{Source: "fields"}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			store.Update(sess.ID, func(s *models.Session) {
+				session.SetFieldType(s, "purchase_amount", []string{"text", "currency"}[i%2], nil, "", by)
+				session.AddField(s, fmt.Sprintf("extra_%d", i), "", "text", nil, "")
+			})
+			if i == 0 {
+				close(started)
+			}
+		}
+	}()
+
+	<-started
+	for i := 0; i < 50; i++ {
+		body := []byte(`{"message": "Company Name: Acme Inc.\npurchase amount = 500000"}`)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/message", sess.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	close(done)
+	<-churned
+}
+
+// TestPrefillProposeAndReview tests proposing answers from pasted text and reviewing them
+func TestPrefillProposeAndReview(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "valuation_cap", "investor_name"})
+	require.NoError(t, err)
+
+	body := []byte(`{"text": "TERM SHEET\nCompany Name: Acme Inc.\nValuation Cap: 10000000\nClosing: TBD"}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/prefill", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PrefillResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Len(t, response.Proposals, 2)
+	assert.Equal(t, "Company Name: Acme Inc.", response.Proposals[0].Snippet)
+
+	// Proposals are not answers until accepted
+	assert.Empty(t, sess.Answers)
+
+	// A value that isn't valid for its type stays proposed instead of being saved
+	sess.Proposals["valuation_cap"] = models.AnswerCandidate{Field: "valuation_cap", Value: "N/A"}
+	body = []byte(`{"accept": ["company_name", "valuation_cap"]}`)
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/prefill/review", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc."}, sess.Answers)
+	assert.Contains(t, w.Body.String(), `"invalid":{"valuation_cap":`)
+	require.Contains(t, sess.Proposals, "valuation_cap")
+
+	body = []byte(`{"reject": ["valuation_cap"]}`)
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/prefill/review", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, sess.Proposals)
+}
+
+// TestAnswerHistoryUndoAndRevert tests the audit trail and reverting answers
+func TestAnswerHistoryUndoAndRevert(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "investor_name"})
+	require.NoError(t, err)
+
+	submit := func(field, answer string) {
+		body, _ := json.Marshal(models.AnswerRequest{Field: field, Answer: answer})
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/answers", sess.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "counsel@example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	submit("company_name", "Acme")
+	submit("investor_name", "Jane")
+	submit("company_name", "Acme Inc.")
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/history?field=company_name", sess.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var history models.HistoryResponse
+	err = json.Unmarshal(w.Body.Bytes(), &history)
+	require.NoError(t, err)
+	require.Len(t, history.Events, 2)
+	assert.Nil(t, history.Events[0].OldValue)
+	assert.Equal(t, "Acme", *history.Events[1].OldValue)
+	assert.Equal(t, "Acme Inc.", *history.Events[1].NewValue)
+	assert.Equal(t, "counsel@example.com", history.Events[1].Actor)
+	assert.Equal(t, "owner", history.Events[1].Role)
+
+	// Undo restores the previous company name
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/undo", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Acme", sess.Answers["company_name"])
+
+	// Reverting to event 1 drops the investor answer, and the revert itself is audited
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/revert", sess.ID), bytes.NewBufferString(`{"eventId": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"company_name": "Acme"}, sess.Answers)
+	assert.Len(t, sess.History, 5)
+	assert.Equal(t, "revert", sess.History[4].Source)
+}
+
+// TestSkipClearAndRevisit tests skipping, optional fields and clearing answers in the interview
+func TestSkipClearAndRevisit(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "investor_name", "notes"})
+	require.NoError(t, err)
+
+	next := func() models.QuestionResponse {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/next", sess.ID), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var q models.QuestionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+		return q
+	}
+
+	// Notes are optional, company name is deferred
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/session/%s/fields/notes/optional", sess.ID), bytes.NewBufferString(`{"optional": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/skip", sess.ID), bytes.NewBufferString(`{"field": "company_name"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Required fields first, then optional, then skipped fields are revisited
+	assert.Equal(t, "investor_name", next().Field)
+	sess.Answers["investor_name"] = "Jane"
+	assert.Equal(t, "notes", next().Field)
+	sess.Answers["notes"] = ""
+	q := next()
+	assert.Equal(t, "company_name", q.Field)
+	assert.True(t, q.Skipped)
+
+	// Jumping to an answered field shows its current answer
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/questions/investor_name", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+	assert.Equal(t, "Jane", q.Answer)
+
+	// Clearing an answer puts the field back in the queue
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/api/session/%s/answers/investor_name", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, sess.Answers, "investor_name")
+	assert.Equal(t, "investor_name", next().Field)
+}
+
+// TestNextQuestionFollowsSections tests that interview sections are kept together
+func TestNextQuestionFollowsSections(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "purchase_amount", "investor_name", "effective_date"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"company_name", "investor_name", "purchase_amount", "effective_date"}, sess.Fields)
+
+	sess.Answers["company_name"] = "Acme Inc."
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/next", sess.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var q models.QuestionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+	assert.Equal(t, "investor_name", q.Field)
+	assert.Equal(t, "parties", q.Group)
+}
+
+// TestConditionalFields tests that dependent questions are only asked when their condition holds
+func TestConditionalFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"has_discount", "discount_rate"})
+	require.NoError(t, err)
+
+	setCondition := func(field, body string) int {
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/session/%s/fields/%s/condition", sess.ID, field), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	next := func() models.QuestionResponse {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/next", sess.ID), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var q models.QuestionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+		return q
+	}
+
+	assert.Equal(t, http.StatusOK, setCondition("discount_rate", `{"field": "has_discount", "op": "equals", "value": "yes"}`))
+	assert.Equal(t, http.StatusBadRequest, setCondition("has_discount", `{"field": "discount_rate", "op": "answered"}`))
+	assert.Equal(t, http.StatusBadRequest, setCondition("discount_rate", `{"field": "has_discount", "op": "matches"}`))
+
+	// Without a discount the rate is never asked
+	sess.Answers["has_discount"] = "No"
+	assert.True(t, next().Done)
+	values, err := session.FillValues(sess)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"has_discount": "No", "discount_rate": ""}, values)
+
+	sess.Answers["has_discount"] = "Yes"
+	assert.Equal(t, "discount_rate", next().Field)
+}
+
+// TestComputedFields tests that computed fields are calculated instead of asked
+func TestComputedFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"valuation_cap", "purchase_amount", "post_money_valuation"})
+	require.NoError(t, err)
+
+	setComputed := func(field, expression string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ComputedRequest{Expression: expression})
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/session/%s/fields/%s/computed", sess.ID, field), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, setComputed("post_money_valuation", "valuation_cap +").Code)
+	assert.Equal(t, http.StatusBadRequest, setComputed("post_money_valuation", "unknown_field * 2").Code)
+	assert.Equal(t, http.StatusBadRequest, setComputed("valuation_cap", "valuation_cap + 1").Code)
+
+	w := setComputed("post_money_valuation", "format_number(valuation_cap + purchase_amount)")
+	require.Equal(t, http.StatusOK, w.Code)
+	var computed models.ComputedField
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &computed))
+	assert.Contains(t, computed.Error, "no answer yet")
+
+	sess.Answers["valuation_cap"] = "$5,000,000"
+	sess.Answers["purchase_amount"] = "$500,000"
+
+	// Computed fields are never asked and can't be answered directly
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/next", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var q models.QuestionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+	assert.True(t, q.Done)
+
+	body, _ := json.Marshal(models.AnswerRequest{Field: "post_money_valuation", Answer: "1"})
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/answers", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var status models.SessionStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "5,500,000", status.Computed["post_money_valuation"].Value)
+	assert.True(t, status.IsCompleted)
+}
+
+// TestMergeAndSplitFields tests folding duplicate placeholder spellings into one field
+func TestMergeAndSplitFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "company", "name_of_company", "investor_name"})
+	require.NoError(t, err)
+	sess.Answers["company"] = "Acme Inc."
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/session/%s/merges", sess.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var suggestions models.MergeSuggestionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestions))
+	require.Len(t, suggestions.Suggestions, 1)
+	assert.Equal(t, "company_name", suggestions.Suggestions[0].Canonical)
+	assert.Equal(t, []string{"company", "name_of_company"}, suggestions.Suggestions[0].Aliases)
+
+	body, _ := json.Marshal(models.MergeRequest{Canonical: "company_name", Aliases: []string{"company", "name_of_company"}})
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/merge", sess.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The alias answer moved to the canonical field, and every spelling is filled with it
+	assert.Equal(t, []string{"company_name", "investor_name"}, sess.Fields)
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc."}, sess.Answers)
+	sess.Answers["investor_name"] = "Jane"
+	values, err := session.FillValues(sess)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Inc.", values["name_of_company"])
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/split", sess.ID), bytes.NewBufferString(`{"field": "company"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"company_name", "company", "investor_name"}, sess.Fields)
+	assert.Equal(t, map[string]string{"name_of_company": "company_name"}, sess.Aliases)
+}
+
+// TestEditFields tests adding, renaming, retyping and removing fields by hand
+func TestEditFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "signature_line", "purchase_amount"})
+	require.NoError(t, err)
+	sess.Answers["company_name"] = "Acme Inc."
+	sess.Questions["company_name"] = "What's the company called?"
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, fmt.Sprintf("/api/session/%s%s", sess.ID, path), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("POST", "/fields", `{"field": "Investor Name", "placeholder": "[INVESTOR]"}`))
+	assert.Equal(t, http.StatusConflict, send("POST", "/fields", `{"field": "company_name"}`))
+	assert.Equal(t, "[INVESTOR]", sess.Placeholders["investor_name"])
+	assert.Equal(t, "parties", sess.FieldGroups["investor_name"])
+
+	// Renaming carries the answer and question over, and the old key still fills its placeholder
+	assert.Equal(t, http.StatusOK, send("PATCH", "/fields/company_name", `{"name": "issuer_name"}`))
+	assert.Equal(t, "Acme Inc.", sess.Answers["issuer_name"])
+	assert.NotContains(t, sess.Answers, "company_name")
+	assert.Equal(t, "What's the company called?", sess.Questions["issuer_name"])
+	assert.Equal(t, "issuer_name", sess.Aliases["company_name"])
+
+	assert.Equal(t, `name contains the word "amount"`, sess.FieldTypeReasons["purchase_amount"])
+	assert.Equal(t, http.StatusOK, send("PATCH", "/fields/purchase_amount", `{"type": "text"}`))
+	assert.Equal(t, "text", sess.FieldTypes["purchase_amount"])
+	assert.Equal(t, "set by hand", sess.FieldTypeReasons["purchase_amount"])
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/fields/purchase_amount", `{"type": "colour"}`))
+
+	// A rejected edit changes nothing, not even the name
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/fields/purchase_amount", `{"name": "price", "type": "bogus"}`))
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/fields/purchase_amount", `{"name": "price", "format": "single_line"}`))
+	assert.Contains(t, sess.Fields, "purchase_amount")
+	assert.NotContains(t, sess.Aliases, "purchase_amount")
+
+	assert.Equal(t, http.StatusOK, send("DELETE", "/fields/signature_line", ""))
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/fields/signature_line", ""))
+	assert.Equal(t, []string{"issuer_name", "investor_name", "purchase_amount"}, sess.Fields)
+	assert.NotContains(t, sess.FieldTypes, "signature_line")
+}
+
+// TestTypedAnswers tests that answers are validated and normalized for their field type
+func TestTypedAnswers(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"purchase_amount", "investor_email"})
+	require.NoError(t, err)
+
+	submit := func(field, answer string) int {
+		body, _ := json.Marshal(models.AnswerRequest{Field: field, Answer: answer})
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/answers", sess.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, submit("investor_email", "not an email"))
+	assert.Equal(t, http.StatusOK, submit("investor_email", "jane@example.com"))
+	assert.Equal(t, http.StatusOK, submit("purchase_amount", "$500k"))
+	assert.Equal(t, "500000", sess.Answers["purchase_amount"])
+
+	values, err := session.FillValues(sess)
+	require.NoError(t, err)
+	assert.Equal(t, "$500,000", values["purchase_amount"])
+}
+
+// TestAddressSubFields tests answering an address in parts and choosing its layout
+func TestAddressSubFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"premises_address"})
+	require.NoError(t, err)
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, fmt.Sprintf("/api/session/%s%s", sess.ID, path), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	question := questionFor(sess, "premises_address")
+	require.Len(t, question.SubFields, 5)
+	assert.Equal(t, "street", question.SubFields[0].Name)
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/answers", `{"field": "premises_address", "parts": {"street": "1 Main St", "city": "Springfield", "region": "IL", "postalCode": "627"}}`))
+	assert.Equal(t, http.StatusOK, send("POST", "/answers", `{"field": "premises_address", "parts": {"street": "1 Main St", "city": "Springfield", "region": "IL", "postalCode": "62701"}}`))
+
+	values, err := session.FillValues(sess)
+	require.NoError(t, err)
+	assert.Equal(t, "1 Main St, Springfield, IL 62701", values["premises_address"])
+
+	assert.Equal(t, http.StatusOK, send("PATCH", "/fields/premises_address", `{"format": "multi_line"}`))
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/fields/premises_address", `{"format": "diagonal"}`))
+	values, err = session.FillValues(sess)
+	require.NoError(t, err)
+	assert.Equal(t, "1 Main St\nSpringfield, IL 62701", values["premises_address"])
+}
+
+// TestBindPartyToRole tests filling a role's fields from a saved party and reusing it
+func TestBindPartyToRole(t *testing.T) {
+	router, store := setupTestRouter()
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/parties", `{"legalName": "Acme Inc.", "entityType": "corp", "jurisdiction": "DE", "address": "1 Main St, Wilmington, DE 19801", "signatoryName": "Jane Doe", "signatoryTitle": "CEO"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var party models.Party
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &party))
+	assert.Equal(t, "corporation", party.EntityType)
+	assert.Equal(t, "Delaware", party.Jurisdiction)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/parties", `{"entityType": "LLC"}`).Code)
+
+	fields := []string{"company_name", "company_entity_type", "company_state", "company_address", "company_signatory_name", "company_signatory_title", "investor_name", "purchase_amount"}
+	sess, err := store.Create([]byte("mock docx bytes"), fields)
+	require.NoError(t, err)
+
+	w = send("PUT", fmt.Sprintf("/session/%s/roles/Company", sess.ID), fmt.Sprintf(`{"partyId": %q}`, party.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp models.BindRoleResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Filled, 6)
+	assert.Equal(t, "Acme Inc.", sess.Answers["company_name"])
+	assert.Equal(t, "corporation", sess.Answers["company_entity_type"])
+	assert.Equal(t, "Delaware", sess.Answers["company_state"])
+	assert.Equal(t, "CEO", sess.Answers["company_signatory_title"])
+	assert.NotContains(t, sess.Answers, "investor_name")
+	assert.Equal(t, party.ID, sess.Parties["company"])
+
+	// The same party fills a different role in a later session
+	other, err := store.Create([]byte("mock docx bytes"), []string{"name_of_investor", "investor_email"})
+	require.NoError(t, err)
+	w = send("PUT", fmt.Sprintf("/session/%s/roles/investor", other.ID), fmt.Sprintf(`{"partyId": %q}`, party.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Acme Inc.", other.Answers["name_of_investor"])
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", fmt.Sprintf("/session/%s/roles/lender", other.ID), `{"party": {"legalName": "Bank"}}`).Code)
+	w = send("GET", "/parties", "")
+	var list models.PartiesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Parties, 1)
+}
+
+// buildDocx creates a real .docx with one paragraph per line
+func buildDocx(t *testing.T, lines ...string) []byte {
+	var body strings.Builder
+	for _, line := range lines {
+		body.WriteString(`<w:p><w:r><w:t xml:space="preserve">` + html.EscapeString(line) + `</w:t></w:r></w:p>`)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`,
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body.String() + `</w:body></w:document>`,
+	} {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+// TestPackageSharesOneInterview tests uploading several documents, answering once and downloading a ZIP
+func TestPackageSharesOneInterview(t *testing.T) {
+	router, store := setupTestRouter()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	// Documents are uploaded in order; the first to use a field names it
+	for _, doc := range []struct {
+		name  string
+		lines []string
+	}{
+		{"safe.docx", []string{"This SAFE is issued by [Company Name] to {{investor_name}}.", "Purchase Amount: $[Purchase Amount]"}},
+		{"side_letter.docx", []string{"Side letter between {{name_of_company}} and {{investor_name}}."}},
+	} {
+		part, err := writer.CreateFormFile("documents", doc.name)
+		require.NoError(t, err)
+		part.Write(buildDocx(t, doc.lines...))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/packages", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp models.PackageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Documents, 2)
+	assert.ElementsMatch(t, []string{"company_name", "investor_name", "purchase_amount"}, resp.Fields)
+	require.Len(t, resp.Merged, 1)
+	assert.Equal(t, []string{"name_of_company"}, resp.Merged[0].Aliases)
+
+	sess, err := store.Get(resp.SessionID)
+	require.NoError(t, err)
+	for field, answer := range map[string]string{"company_name": "Acme Inc.", "investor_name": "Jane Doe", "purchase_amount": "50000"} {
+		submitBody, _ := json.Marshal(models.AnswerRequest{Field: field, Answer: answer})
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/answers", sess.ID), bytes.NewBuffer(submitBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest("POST", fmt.Sprintf("/api/session/%s/generate", sess.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	texts := make(map[string]string)
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		text, err := docx.ExtractText(data)
+		require.NoError(t, err)
+		texts[f.Name] = text
+	}
+	assert.Contains(t, texts["safe.docx"], "issued by Acme Inc. to Jane Doe")
+	assert.Contains(t, texts["safe.docx"], "Purchase Amount: $50,000")
+	assert.Contains(t, texts["side_letter.docx"], "between Acme Inc. and Jane Doe")
+}
+
+// TestCollaboratorsAnswerOnlyTheirFields tests that invited roles answer only the fields assigned to them
+func TestCollaboratorsAnswerOnlyTheirFields(t *testing.T) {
+	router, store := setupTestRouter()
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Session-Token", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "investor_name", "purchase_amount"})
+	require.NoError(t, err)
+	base := "/session/" + sess.ID
+
+	// Inviting a collaborator locks the session to token holders
+	w := send("POST", base+"/collaborators", "", `{"role": "Investor", "fields": ["investor_name", "purchase_amount"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var invite models.CollaboratorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invite))
+	assert.Equal(t, "investor", invite.Role)
+	assert.Contains(t, invite.Link, "?token="+invite.Token)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", base+"/next", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", base+"/next", "wrong", "").Code)
+
+	// The investor is asked and may answer only their own fields
+	w = send("GET", base+"/next?token="+invite.Token, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var question models.QuestionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &question))
+	assert.Equal(t, "investor_name", question.Field)
+	assert.Equal(t, 2, question.Total)
+	assert.Equal(t, http.StatusForbidden, send("POST", base+"/answers", invite.Token, `{"field": "company_name", "answer": "Acme"}`).Code)
+	assert.Equal(t, http.StatusOK, send("POST", base+"/answers", invite.Token, `{"field": "investor_name", "answer": "Jane"}`).Code)
+	assert.Equal(t, http.StatusOK, send("POST", base+"/answers", invite.Token, `{"field": "purchase_amount", "answer": "100000"}`).Code)
+	// History records the role the token granted
+	require.Len(t, sess.History, 2)
+	assert.Equal(t, "investor", sess.History[0].Role)
+	w = send("GET", base+"/next", invite.Token, "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &question))
+	assert.True(t, question.Done)
+
+	// Owner-only routes stay closed to collaborators
+	assert.Equal(t, http.StatusForbidden, send("GET", base+"/collaborators", invite.Token, "").Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", base+"/generate", invite.Token, "").Code)
+
+	// The owner answers the rest and sees everyone's progress
+	assert.Equal(t, http.StatusOK, send("POST", base+"/answers", sess.OwnerToken, `{"field": "company_name", "answer": "Acme"}`).Code)
+	w = send("GET", base+"/collaborators", sess.OwnerToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var progress models.CollaboratorsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	require.Len(t, progress.Collaborators, 1)
+	assert.Equal(t, 2, progress.Collaborators[0].Answered)
+	assert.True(t, progress.Collaborators[0].Done)
+	assert.Equal(t, []string{"company_name"}, progress.Unassigned)
+	assert.True(t, progress.IsCompleted)
+
+	// Removing the last collaborator opens the session to anyone with its ID again
+	assert.Equal(t, http.StatusOK, send("DELETE", base+"/collaborators/investor", sess.OwnerToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", base+"/collaborators/investor", "", "").Code)
+	assert.Equal(t, http.StatusOK, send("GET", base+"/next", "", "").Code)
+}
+
+// TestSessionEventsStreamAnswers tests that other users' answers reach a session's watchers until it is deleted
+func TestSessionEventsStreamAnswers(t *testing.T) {
+	router, store := setupTestRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "investor_name"})
+	require.NoError(t, err)
+
+	resp, err := http.Get(server.URL + "/api/session/" + sess.ID + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	// next reads the stream up to the next event
+	lines := bufio.NewScanner(resp.Body)
+	next := func() (string, models.SessionEvent) {
+		var name string
+		var event models.SessionEvent
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event))
+			case line == "" && name != "":
+				return name, event
+			}
+		}
+		t.Fatal("stream ended")
+		return "", event
+	}
+
+	name, event := next()
+	assert.Equal(t, "ready", name)
+	assert.Equal(t, 2, event.Total)
+
+	// Another user's answer reaches the watcher
+	body := bytes.NewBufferString(`{"field": "company_name", "answer": "Acme Inc."}`)
+	answer, err := http.Post(server.URL+"/api/session/"+sess.ID+"/answers", "application/json", body)
+	require.NoError(t, err)
+	answer.Body.Close()
+
+	name, event = next()
+	assert.Equal(t, "answer", name)
+	assert.Equal(t, "company_name", event.Field)
+	require.NotNil(t, event.Answer)
+	assert.Equal(t, "Acme Inc.", *event.Answer)
+	assert.Equal(t, 1, event.Progress)
+
+	// Deleting the session ends the stream
+	require.NoError(t, store.Delete(sess.ID))
+	assert.False(t, lines.Scan())
+}
+
+// TestSessionEventsConcurrentEdits tests that a collaborator's stream filters answers
+// safely while the session's fields and collaborators are being edited (run with -race)
+func TestSessionEventsConcurrentEdits(t *testing.T) {
+	router, store := setupTestRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "investor_name"})
+	require.NoError(t, err)
+	var investor models.Collaborator
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) {
+		investor, err = session.SetCollaborator(s, "investor", []string{"investor_name"})
+	}))
+	require.NoError(t, err)
+
+	resp, err := http.Get(server.URL + "/api/session/" + sess.ID + "/events?token=" + investor.Token)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Keep reassigning roles and adding fields while answers stream
+	done := make(chan struct{})
+	churned := make(chan struct{})
+	go func() {
+		defer close(churned)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			store.Update(sess.ID, func(s *models.Session) {
+				session.SetCollaborator(s, "counsel", []string{"company_name"})
+				session.AddField(s, fmt.Sprintf("extra_%d", i), "", "text", nil, "")
+			})
+		}
+	}()
+
+	// Answer both fields. Each round waits for the stream to catch up so no event is dropped.
+	const rounds = 20
+	by := session.Editor{Source: "answer"}
+	received := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < rounds; i++ {
+			store.Update(sess.ID, func(s *models.Session) {
+				session.SetAnswer(s, "company_name", fmt.Sprintf("Acme %d", i), by)
+			})
+			store.Update(sess.ID, func(s *models.Session) {
+				session.SetAnswer(s, "investor_name", fmt.Sprintf("Jane %d", i), by)
+			})
+			<-received
+		}
+	}()
+
+	// The investor sees only their own field's answers
+	lines := bufio.NewScanner(resp.Body)
+	seen := 0
+	for seen < rounds && lines.Scan() {
+		line := lines.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event models.SessionEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event))
+		if event.Type == session.EventAnswer {
+			assert.Equal(t, "investor_name", event.Field)
+			seen++
+			received <- true
+		}
+	}
+	<-done
+	<-churned
+	assert.Equal(t, rounds, seen)
+}
+
+// TestGenerateQuestionsStream tests streaming AI-phrased questions as the provider writes them
+func TestGenerateQuestionsStream(t *testing.T) {
+	// A fake OpenAI-compatible provider that pauses after the first question
+	release := make(chan struct{})
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(content string) {
+			fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", content)
+			w.(http.Flusher).Flush()
+		}
+		chunk("```json\n{\"company_name\": {\"question\": \"What is the company's legal name?\", \"type\": \"text\"}, \"purchase_")
+		<-release
+		chunk("amount\": {\"question\": \"How much is being invested?\", \"type\": \"currency\"}}\n```")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer provider.Close()
+	t.Setenv("AI_PROVIDER", "")
+	t.Setenv("LLM_BASE_URL", provider.URL)
+
+	router, store := setupTestRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name", "purchase_amount"})
+	require.NoError(t, err)
+
+	resp, err := http.Post(server.URL+"/api/session/"+sess.ID+"/ai/questions?stream=true", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// next reads the stream up to the next event
+	lines := bufio.NewScanner(resp.Body)
+	next := func() (string, string) {
+		var name, data string
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				data = strings.TrimPrefix(line, "data:")
+			case line == "" && name != "":
+				return name, data
+			}
+		}
+		t.Fatal("stream ended")
+		return "", ""
+	}
+
+	// The first question is saved and sent while the provider is still generating
+	name, data := next()
+	require.Equal(t, "question", name)
+	var question models.QuestionResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &question))
+	assert.Equal(t, "company_name", question.Field)
+	assert.True(t, question.IsAIPhrased)
+	assert.Equal(t, "What is the company's legal name?", sess.Questions["company_name"])
+	assert.NotContains(t, sess.Questions, "purchase_amount")
+
+	// Fields can be edited while generation runs
+	added, err := http.Post(server.URL+"/api/session/"+sess.ID+"/fields", "application/json", strings.NewReader(`{"field": "closing_date", "type": "date"}`))
+	require.NoError(t, err)
+	added.Body.Close()
+	require.Equal(t, http.StatusOK, added.StatusCode)
+	close(release)
+
+	name, data = next()
+	require.Equal(t, "question", name)
+	require.NoError(t, json.Unmarshal([]byte(data), &question))
+	assert.Equal(t, "purchase_amount", question.Field)
+	assert.Equal(t, "currency", question.FieldType)
+
+	name, data = next()
+	require.Equal(t, "done", name)
+	var done models.GenerateQuestionsResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &done))
+	assert.Equal(t, 2, done.Count)
+	assert.Equal(t, "local", done.Provider)
+}
+
+// TestGenerateQuestionsSkipsUnknownFields tests that questions for fields the session doesn't have are dropped
+func TestGenerateQuestionsSkipsUnknownFields(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := `{"company_name": {"question": "What is the company's legal name?", "type": "text"}, "made_up": {"question": "What else?", "type": "date"}}`
+		json.NewEncoder(w).Encode(map[string]any{"choices": []map[string]any{{"message": map[string]string{"content": content}}}})
+	}))
+	defer provider.Close()
+	t.Setenv("AI_PROVIDER", "")
+	t.Setenv("LLM_BASE_URL", provider.URL)
+	router, store := setupTestRouter()
+
+	sess, err := store.Create([]byte("mock docx bytes"), []string{"company_name"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/api/session/"+sess.ID+"/ai/questions", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response models.GenerateQuestionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+	assert.Equal(t, map[string]string{"company_name": "What is the company's legal name?"}, response.Questions)
+	assert.NotContains(t, sess.Questions, "made_up")
+	assert.NotContains(t, sess.FieldTypes, "made_up")
+}
+
+// TestAsyncUploadAndGenerateJobs tests running upload and generation as background jobs
+func TestAsyncUploadAndGenerateJobs(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	send := func(method, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api"+path, body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// finish polls a job until it is done
+	finish := func(id string) models.Job {
+		var job models.Job
+		require.Eventually(t, func() bool {
+			w := send("GET", "/jobs/"+id, nil, "")
+			require.Equal(t, http.StatusOK, w.Code)
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+			return job.FinishedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("document", "safe.docx")
+	require.NoError(t, err)
+	part.Write(buildDocx(t, "This SAFE is issued by {{company_name}} to {{investor_name}}."))
+	writer.Close()
+
+	// Upload returns a job at once; its result is the usual upload response
+	w := send("POST", "/upload?async=true", body, writer.FormDataContentType())
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job models.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "upload", job.Kind)
+	assert.Equal(t, "/api/jobs/"+job.ID, w.Header().Get("Location"))
+
+	job = finish(job.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	result, _ := json.Marshal(job.Result)
+	var upload models.UploadResponse
+	require.NoError(t, json.Unmarshal(result, &upload))
+	assert.Equal(t, job.SessionID, upload.SessionID)
+	assert.ElementsMatch(t, []string{"company_name", "investor_name"}, upload.Fields)
+
+	for field, answer := range map[string]string{"company_name": "Acme Inc.", "investor_name": "Jane Doe"} {
+		submitBody, _ := json.Marshal(models.AnswerRequest{Field: field, Answer: answer})
+		require.Equal(t, http.StatusOK, send("POST", "/session/"+upload.SessionID+"/answers", bytes.NewBuffer(submitBody), "application/json").Code)
+	}
+
+	// Generation runs in the background and the document is downloaded from the job
+	w = send("POST", "/session/"+upload.SessionID+"/generate?async=true", nil, "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+
+	// Edits made after generation was queued don't reach the document
+	by := session.Editor{Source: "answer"}
+	require.NoError(t, store.Update(upload.SessionID, func(s *models.Session) {
+		session.SetAnswer(s, "investor_name", "John Roe", by)
+		session.AddField(s, "closing_date", "{{closing_date}}", "date", nil, "")
+	}))
+
+	job = finish(job.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	require.Equal(t, "/api/jobs/"+job.ID+"/download", job.DownloadURL)
+
+	w = send("GET", "/jobs/"+job.ID+"/download", nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "filled_document.docx")
+	filled, err := docx.ExtractText(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Contains(t, filled, "issued by Acme Inc. to Jane Doe")
+
+	// Package and reverse uploads are queued the same way
+	body = &bytes.Buffer{}
+	writer = multipart.NewWriter(body)
+	for name, line := range map[string]string{"safe.docx": "Issued by {{company_name}}.", "side_letter.docx": "Signed by {{investor_name}}."} {
+		part, err := writer.CreateFormFile("documents", name)
+		require.NoError(t, err)
+		part.Write(buildDocx(t, line))
+	}
+	writer.Close()
+	w = send("POST", "/packages?async=true", body, writer.FormDataContentType())
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "package", job.Kind)
+	job = finish(job.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	result, _ = json.Marshal(job.Result)
+	var pkg models.PackageResponse
+	require.NoError(t, json.Unmarshal(result, &pkg))
+	assert.Len(t, pkg.Documents, 2)
+	assert.ElementsMatch(t, []string{"company_name", "investor_name"}, pkg.Fields)
+
+	body = &bytes.Buffer{}
+	writer = multipart.NewWriter(body)
+	for name, line := range map[string]string{"template": "Issued by {{company_name}} today.", "filled": "Issued by Acme Inc. today."} {
+		part, err := writer.CreateFormFile(name, name+".docx")
+		require.NoError(t, err)
+		part.Write(buildDocx(t, line))
+	}
+	writer.Close()
+	w = send("POST", "/reverse?async=true", body, writer.FormDataContentType())
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "reverse", job.Kind)
+	job = finish(job.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	result, _ = json.Marshal(job.Result)
+	var reversed models.ReverseResponse
+	require.NoError(t, json.Unmarshal(result, &reversed))
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc."}, reversed.Answers)
+
+	assert.Equal(t, http.StatusNotFound, send("GET", "/jobs/missing", nil, "").Code)
+}
+
+// TestReloadPromptsNeedsAdminToken tests that only the admin can reload prompt templates
+func TestReloadPromptsNeedsAdminToken(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer " + testAdminToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/prompts/reload", nil)
+		req.Header.Set("Authorization", tt.auth)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Code, tt.auth)
+	}
+}
+
+// TestWebhookLifecycleDeliveries tests managing a webhook and delivering a session's lifecycle events to it
+func TestWebhookLifecycleDeliveries(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	// The receiver listens on localhost
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+	router, _ := setupTestRouter()
+
+	var mu sync.Mutex
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("X-Webhook-Event"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	send := func(method, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api"+path, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Webhooks need the admin token
+	for _, auth := range []string{"", "Bearer wrong", testAdminToken} {
+		req := httptest.NewRequest("GET", "/api/webhooks", nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, auth)
+		assert.Contains(t, w.Body.String(), "admin_token_required")
+	}
+
+	w := send("POST", "/webhooks", strings.NewReader(`{"url": "not a url"}`), "application/json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_webhook")
+
+	w = send("POST", "/webhooks", strings.NewReader(`{"url": "`+receiver.URL+`", "template": "safe.docx"}`), "application/json")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var hook models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hook))
+	assert.NotEmpty(t, hook.Secret)
+
+	// The test delivery is sent once and reported straight away
+	w = send("POST", "/webhooks/"+hook.ID+"/test", nil, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var delivery models.WebhookDelivery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delivery))
+	assert.Equal(t, webhooks.EventTest, delivery.Event)
+	assert.Equal(t, webhooks.StatusSucceeded, delivery.Status)
+	require.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("document", "safe.docx")
+	require.NoError(t, err)
+	part.Write(buildDocx(t, "This SAFE is issued by {{company_name}} to {{investor_name}}."))
+	writer.Close()
+
+	w = send("POST", "/upload", body, writer.FormDataContentType())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var upload models.UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+
+	for _, answer := range []models.AnswerRequest{{Field: "company_name", Answer: "Acme Inc."}, {Field: "investor_name", Answer: "Jane Doe"}} {
+		submitBody, _ := json.Marshal(answer)
+		require.Equal(t, http.StatusOK, send("POST", "/session/"+upload.SessionID+"/answers", bytes.NewBuffer(submitBody), "application/json").Code)
+	}
+	require.Equal(t, http.StatusOK, send("POST", "/session/"+upload.SessionID+"/generate", nil, "").Code)
+
+	// Created, completed and generated are each delivered once, newest first in the log
+	var deliveries models.DeliveriesResponse
+	require.Eventually(t, func() bool {
+		w := send("GET", "/webhooks/"+hook.ID+"/deliveries", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+		for _, d := range deliveries.Deliveries {
+			if d.Status != webhooks.StatusSucceeded {
+				return false
+			}
+		}
+		return len(deliveries.Deliveries) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	events := []string{}
+	for _, d := range deliveries.Deliveries {
+		events = append(events, d.Event)
+		if d.Event != webhooks.EventTest {
+			assert.Equal(t, upload.SessionID, d.SessionID)
+		}
+	}
+	assert.ElementsMatch(t, []string{webhooks.EventTest, webhooks.EventSessionCreated, webhooks.EventSessionCompleted, webhooks.EventSessionGenerated}, events)
+	mu.Lock()
+	assert.ElementsMatch(t, events, received)
+	mu.Unlock()
+
+	// Listing never shows the secret again
+	w = send("GET", "/webhooks", nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), hook.Secret)
+
+	require.Equal(t, http.StatusOK, send("DELETE", "/webhooks/"+hook.ID, nil, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", "/webhooks/"+hook.ID+"/test", nil, "").Code)
+}
+
+// TestAdminAccessDisabled tests that admin routes are closed when no admin token is set
+func TestAdminAccessDisabled(t *testing.T) {
+	router := gin.New()
+	router.GET("/api/webhooks", AdminAccess(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/api/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "admin_disabled")
+}
+
+// TestReverseExtract tests recovering answers from a filled copy of a template
+func TestReverseExtract(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "none")
+	router, store := setupTestRouter()
+
+	reverse := func(documentType string, filled ...string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("template", "safe.docx")
+		require.NoError(t, err)
+		part.Write(buildDocx(t, "Issued by {{company_name}} for {{purchase_amount}}."))
+		part, err = writer.CreateFormFile("filled", "acme.docx")
+		require.NoError(t, err)
+		part.Write(buildDocx(t, filled...))
+		if documentType != "" {
+			writer.WriteField("documentType", documentType)
+		}
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/api/reverse", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := reverse("mortgage", "Issued by Acme Inc. for $1,000,000.")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_document_type")
+
+	// The response reports answers as they were saved, normalized for their type
+	w = reverse("", "Issued by Acme Inc. for $1,000,000.")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response models.ReverseResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc.", "purchase_amount": "1000000"}, response.Answers)
+
+	saved, err := store.Get(response.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, response.Answers, saved.Answers)
+
+	// A recovered value that doesn't fit its field's type is reported, not saved
+	w = reverse("", "Issued by Acme Inc. for N/A.")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	response = models.ReverseResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc."}, response.Answers)
+	assert.Contains(t, response.Rejected, "purchase_amount")
+
+	saved, err = store.Get(response.SessionID)
+	require.NoError(t, err)
+	assert.NotContains(t, saved.Answers, "purchase_amount")
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleGetHistory lists every answer change for a session, optionally for one field (?field=)
+func HandleGetHistory(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		field := c.Query("field")
+		events := []models.AnswerEvent{}
+		for _, e := range sess.History {
+			if field == "" || e.Field == field {
+				events = append(events, e)
+			}
+		}
+
+		c.JSON(http.StatusOK, models.HistoryResponse{
+			SessionID: sess.ID,
+			Events:    events,
+		})
+	}
+}
+
+// HandleUndo reverts the most recent answer change
+func HandleUndo(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handleRevert(c, store, func(s *models.Session, by session.Editor) (int, error) {
+			return session.Undo(s, by)
+		})
+	}
+}
+
+// HandleRevert restores answers to how they were right after a given history event
+func HandleRevert(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RevertRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: eventId (0 reverts to before any answers)",
+			})
+			return
+		}
+
+		handleRevert(c, store, func(s *models.Session, by session.Editor) (int, error) {
+			return session.RevertTo(s, *req.EventID, by)
+		})
+	}
+}
+
+// handleRevert applies a revert function to the session and reports the result
+func handleRevert(c *gin.Context, store *session.Store, revert func(*models.Session, session.Editor) (int, error)) {
+	sessionID := c.Param("id")
+
+	var changed int
+	var revertErr error
+	err := store.Update(sessionID, func(s *models.Session) {
+		changed, revertErr = revert(s, editor(c, "revert"))
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "session_not_found",
+			Message: "Session not found.",
+		})
+		return
+	}
+	if errors.Is(revertErr, session.ErrEventNotFound) || errors.Is(revertErr, session.ErrNothingToUndo) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_revert",
+			Message: revertErr.Error(),
+		})
+		return
+	}
+
+	sess, _ := store.Get(sessionID)
+	progress, total := session.Completion(sess)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Answers reverted successfully.",
+		"changed":  changed,
+		"answers":  sess.Answers,
+		"progress": progress,
+		"total":    total,
+	})
+}
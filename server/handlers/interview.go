@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleClearAnswer deletes the answer for a field so it is asked again
+func HandleClearAnswer(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field := c.Param("field")
+		updateField(c, store, field, func(s *models.Session) {
+			session.ClearAnswer(s, field, editor(c, "clear"))
+		})
+	}
+}
+
+// HandleSkipField defers a field; it is asked again once everything else is answered
+func HandleSkipField(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.FieldRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: field",
+			})
+			return
+		}
+
+		updateField(c, store, req.Field, func(s *models.Session) {
+			s.Skipped[req.Field] = true
+		})
+	}
+}
+
+// HandleSetOptional marks a field as optional (may be left blank) or required
+func HandleSetOptional(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.OptionalRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: optional",
+			})
+			return
+		}
+
+		field := c.Param("field")
+		updateField(c, store, field, func(s *models.Session) {
+			if req.Optional {
+				s.Optional[field] = true
+			} else {
+				delete(s.Optional, field)
+			}
+		})
+	}
+}
+
+// HandleGetQuestion jumps to a specific field, answered or not, so it can be revisited
+func HandleGetQuestion(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		field := c.Param("field")
+		if !session.HasField(sess, field) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "invalid_field",
+				Message: "Field '" + field + "' does not exist in this document.",
+			})
+			return
+		}
+		if !checkAssigned(c, sess, field) {
+			return
+		}
+
+		c.JSON(http.StatusOK, questionFor(sess, field))
+	}
+}
+
+// updateField applies fn to the session after checking the field exists, then responds
+// with the field's current question
+func updateField(c *gin.Context, store *session.Store, field string, fn func(s *models.Session)) {
+	sessionID := c.Param("id")
+
+	sess, err := store.Get(sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "session_not_found",
+			Message: "Session not found.",
+		})
+		return
+	}
+	if !session.HasField(sess, field) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_field",
+			Message: "Field '" + field + "' does not exist in this document.",
+		})
+		return
+	}
+	if !checkAssigned(c, sess, field) {
+		return
+	}
+
+	var response models.QuestionResponse
+	err = store.Update(sessionID, func(s *models.Session) {
+		fn(s)
+		response = questionFor(s, field)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update session.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleSetCondition makes a field depend on another field's answer; it is only asked
+// (and only filled) while the condition holds
+func HandleSetCondition(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.Condition
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: field, op (equals, not_equals, in, answered, not_answered)",
+			})
+			return
+		}
+
+		sessionID := c.Param("id")
+		if _, err := store.Get(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		field := c.Param("field")
+		var response models.QuestionResponse
+		var condErr error
+		err := store.Update(sessionID, func(s *models.Session) {
+			if !session.HasField(s, field) {
+				condErr = fmt.Errorf("%w: field %q does not exist", session.ErrInvalidCondition, field)
+				return
+			}
+			if condErr = session.SetCondition(s, field, req); condErr == nil {
+				response = questionFor(s, field)
+			}
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to update session.",
+			})
+			return
+		}
+		if condErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_condition",
+				Message: condErr.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleDeleteCondition makes a field unconditional again
+func HandleDeleteCondition(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field := c.Param("field")
+		updateField(c, store, field, func(s *models.Session) {
+			delete(s.Conditions, field)
+		})
+	}
+}
+
+// HandleSetComputed makes a field calculated from other answers instead of asked
+func HandleSetComputed(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ComputedRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: expression",
+			})
+			return
+		}
+
+		sessionID := c.Param("id")
+		if _, err := store.Get(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		field := c.Param("field")
+		var response models.ComputedField
+		var exprErr error
+		err := store.Update(sessionID, func(s *models.Session) {
+			if !session.HasField(s, field) {
+				exprErr = fmt.Errorf("%w: field %q does not exist", session.ErrInvalidExpression, field)
+				return
+			}
+			if exprErr = session.SetComputed(s, field, req.Expression); exprErr == nil {
+				response = session.ComputedFields(s)[field]
+			}
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to update session.",
+			})
+			return
+		}
+		if exprErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_expression",
+				Message: exprErr.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleDeleteComputed makes a computed field asked again
+func HandleDeleteComputed(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field := c.Param("field")
+		updateField(c, store, field, func(s *models.Session) {
+			delete(s.Computed, field)
+		})
+	}
+}
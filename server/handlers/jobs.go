@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/jobs"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// runOrQueue does slow work in the request, or with ?async=true queues it and
+// responds 202 with the job to poll
+func runOrQueue(c *gin.Context, queue *jobs.Queue, kind, sessionID string, work jobs.Func) {
+	if c.Query("async") != "true" {
+		respond(c, work(func(int, string) {}))
+		return
+	}
+
+	job, err := queue.Submit(kind, sessionID, work)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "queue_full",
+			Message: "Too many jobs are waiting. Please try again shortly.",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "job_creation_error",
+			Message: "Failed to queue job.",
+		})
+		return
+	}
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// respond sends a job outcome as the synchronous endpoint would
+func respond(c *gin.Context, outcome jobs.Outcome) {
+	if outcome.File != nil {
+		c.Header("Content-Disposition", "attachment; filename="+outcome.FileName)
+		c.Data(outcome.Status, outcome.ContentType, outcome.File)
+		return
+	}
+	c.JSON(outcome.Status, outcome.Body)
+}
+
+// HandleGetJob reports a job's status and progress, and its result once finished
+func HandleGetJob(queue *jobs.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := queue.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "job_not_found",
+				Message: "Job not found. Finished jobs are kept for an hour.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// HandleDownloadJob returns the file a finished job produced, e.g. a generated document
+func HandleDownloadJob(queue *jobs.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		outcome, finished, err := queue.Outcome(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "job_not_found",
+				Message: "Job not found. Finished jobs are kept for an hour.",
+			})
+			return
+		}
+		if !finished {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "job_not_finished",
+				Message: "The job is still running.",
+			})
+			return
+		}
+		if outcome.File == nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "no_file",
+				Message: "This job did not produce a file.",
+			})
+			return
+		}
+		respond(c, outcome)
+	}
+}
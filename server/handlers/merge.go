@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleGetMergeSuggestions lists fields that look like duplicates of each other
+func HandleGetMergeSuggestions(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.MergeSuggestionsResponse{
+			Suggestions: session.SuggestMerges(sess),
+		})
+	}
+}
+
+// HandleMergeFields folds alias fields into a canonical field
+func HandleMergeFields(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MergeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: canonical, aliases",
+			})
+			return
+		}
+
+		handleFieldChange(c, store, func(s *models.Session) error {
+			return session.MergeFields(s, req.Canonical, req.Aliases, editor(c, "merge"))
+		})
+	}
+}
+
+// HandleSplitField turns a merged alias back into its own field
+func HandleSplitField(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.FieldRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: field",
+			})
+			return
+		}
+
+		handleFieldChange(c, store, func(s *models.Session) error {
+			return session.SplitField(s, req.Field)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/extract"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+)
+
+// minAnswerConfidence is the confidence at which an extracted value is saved without confirmation
+const minAnswerConfidence = 0.8
+
+// HandleMessage extracts answers for any pending fields from a free-text chat message,
+// saves the confident ones and returns follow-up questions for the rest
+func HandleMessage(store *session.Store, ledger *usage.Ledger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+
+		var req models.MessageRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: message",
+			})
+			return
+		}
+
+		// Offer every unanswered field to the extractor. The session can change while
+		// the extractor runs, so read it under the lock and check candidates again when saving.
+		var template string
+		var pending []extract.Field
+		err := store.View(sessionID, func(s *models.Session) {
+			template, pending = s.Template, extractFields(s, true)
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		client := meteredClient(ledger, sessionID, template, "extract", ai.ProviderOpenAI, ai.ProviderGemini)
+		candidates, err := extract.FromMessage(client, pending, req.Message)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			c.JSON(http.StatusPaymentRequired, models.ErrorResponse{
+				Error:   "budget_exceeded",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "extraction_failed",
+				Message: "Failed to extract answers: " + err.Error(),
+			})
+			return
+		}
+
+		response := models.MessageResponse{
+			Saved:     []models.AnswerCandidate{},
+			FollowUps: []models.AnswerCandidate{},
+		}
+		by := editor(c, "message")
+		err = store.Update(sessionID, func(s *models.Session) {
+			for _, candidate := range candidates {
+				if !session.HasField(s, candidate.Field) {
+					continue // Removed while the extractor ran
+				}
+				// Values that don't fit the field's type need confirming however sure the extractor is
+				value, err := session.NormalizeAnswer(s, candidate.Field, candidate.Value)
+				if err != nil {
+					candidate.FollowUp = "\"" + candidate.Value + "\" doesn't look right for the " + fieldLabel(candidate.Field) + ". " + humanizeFieldName(candidate.Field)
+					response.FollowUps = append(response.FollowUps, candidate)
+					continue
+				}
+				candidate.Value = value
+
+				if candidate.Confidence >= minAnswerConfidence {
+					session.SetAnswer(s, candidate.Field, candidate.Value, by)
+					response.Saved = append(response.Saved, candidate)
+					continue
+				}
+				if candidate.FollowUp == "" {
+					candidate.FollowUp = "Is \"" + candidate.Value + "\" the correct " + fieldLabel(candidate.Field) + "?"
+				}
+				response.FollowUps = append(response.FollowUps, candidate)
+			}
+			if client != nil {
+				s.PromptVersions[prompts.Extraction] = prompts.Version(prompts.Extraction)
+			}
+			response.Progress, response.Total = session.Completion(s)
+			response.Done = response.Progress == response.Total
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to save answers.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// extractFields describes the session's asked (not computed) fields for the extractor,
+// optionally only unanswered visible ones
+func extractFields(sess *models.Session, pendingOnly bool) []extract.Field {
+	fields := make([]extract.Field, 0, len(sess.Fields))
+	for _, field := range sess.Fields {
+		if session.IsComputed(sess, field) {
+			continue
+		}
+		if _, answered := sess.Answers[field]; pendingOnly && (answered || !session.Visible(sess, field)) {
+			continue
+		}
+		question, ok := sess.Questions[field]
+		if !ok {
+			question = humanizeFieldName(field)
+		}
+		fields = append(fields, extract.Field{Name: field, Type: sess.FieldTypes[field], Question: question})
+	}
+	return fields
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/jobs"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+)
+
+// maxPackageDocuments caps how many templates one package may hold
+const maxPackageDocuments = 10
+
+// HandleUploadPackage creates one session for several documents (multipart field
+// "documents", repeated). Each document is detected with its own profile; their
+// fields are unioned, and look-alike fields from different documents are merged
+// so every value is asked once. With ?async=true detection runs as a background job.
+func HandleUploadPackage(store *session.Store, ledger *usage.Ledger, queue *jobs.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil || len(form.File["documents"]) == 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "missing_file",
+				Message: "No documents uploaded. Please upload .docx files with field name 'documents'.",
+			})
+			return
+		}
+		files := form.File["documents"]
+		if len(files) > maxPackageDocuments {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "too_many_documents",
+				Message: fmt.Sprintf("A package may hold at most %d documents.", maxPackageDocuments),
+			})
+			return
+		}
+
+		// Reserve the session ID up front so detection usage is attributed to it
+		sessionID, err := session.NewID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "session_creation_error",
+				Message: "Failed to create session.",
+			})
+			return
+		}
+
+		// Read every document now; the job may run after the request is gone
+		documents := make([]models.Document, 0, len(files))
+		for _, file := range files {
+			docBytes, name, errResp := readDocxFile(file)
+			if errResp != nil {
+				errResp.Message = file.Filename + ": " + errResp.Message
+				c.JSON(http.StatusBadRequest, errResp)
+				return
+			}
+			documents = append(documents, models.Document{Name: name, OriginalDoc: docBytes})
+		}
+		by := editor(c, "package")
+
+		runOrQueue(c, queue, "package", sessionID, func(report func(int, string)) jobs.Outcome {
+			// Detect each document's placeholders
+			var detections []*docx.Detection
+			var fields []string
+			seen := make(map[string]bool)
+			usedAI := false
+			for i := range documents {
+				doc := &documents[i]
+				report(10+80*i/len(documents), "detecting fields in "+doc.Name)
+				client := meteredClient(ledger, sessionID, doc.Name, "detect", docx.DetectionProviders...)
+				detection, err := docx.DetectFields(doc.OriginalDoc, client, "")
+				if err != nil {
+					status, errResp := detectionError(fmt.Errorf("%s: %w", doc.Name, err))
+					return jobs.Outcome{Status: status, Body: errResp}
+				}
+				usedAI = usedAI || client != nil
+
+				doc.DocumentType, doc.Fields = detection.DocumentType, detection.Fields
+				detections = append(detections, detection)
+				for _, field := range detection.Fields {
+					if !seen[field] {
+						seen[field] = true
+						fields = append(fields, field)
+					}
+				}
+			}
+
+			if len(fields) == 0 {
+				return jobs.Outcome{Status: http.StatusBadRequest, Body: models.ErrorResponse{
+					Error:   "no_fields_found",
+					Message: "No placeholders found in any document. Use {{field_name}} format for placeholders.",
+				}}
+			}
+
+			report(90, "creating session")
+			sess, err := store.CreateWithID(sessionID, nil, fields)
+			if err != nil {
+				return jobs.Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+					Error:   "session_creation_error",
+					Message: "Failed to create session.",
+				}}
+			}
+
+			var merged []models.MergeSuggestion
+			var response models.PackageResponse
+			err = store.Update(sess.ID, func(s *models.Session) {
+				s.Template = packageName(documents)
+				s.Documents = documents
+				// Apply the last document's types first so the first document to use a field wins
+				for i := len(detections) - 1; i >= 0; i-- {
+					s.DocumentType = applyDetectedTypes(s, detections[i]).Name
+				}
+				if usedAI {
+					s.PromptVersions[prompts.Detection] = prompts.Version(prompts.Detection)
+				}
+				merged = session.MergeAcrossDocuments(s, by)
+				session.Notify(s, models.SessionEvent{Type: session.EventCreated})
+				response = models.PackageResponse{
+					SessionID:  s.ID,
+					OwnerToken: s.OwnerToken,
+					Documents:  documents,
+					Fields:     slices.Clone(s.Fields),
+					Merged:     merged,
+					Message:    fmt.Sprintf("%d documents uploaded successfully.", len(documents)),
+				}
+			})
+			if err != nil {
+				return jobs.Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+					Error:   "session_creation_error",
+					Message: "Failed to create session.",
+				}}
+			}
+			return jobs.Outcome{Status: http.StatusOK, Body: response}
+		})
+	}
+}
+
+// packageName names a package after its first document, e.g. "SAFE.docx (+2 more)"
+func packageName(documents []models.Document) string {
+	if len(documents) == 1 {
+		return documents[0].Name
+	}
+	return fmt.Sprintf("%s (+%d more)", documents[0].Name, len(documents)-1)
+}
+
+// fillPackage fills every document in a package and zips them, one entry per document
+func fillPackage(documents []packageDocument, placeholders map[string]string, client ai.Client) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	names := make(map[string]bool)
+	for _, doc := range documents {
+		filled, err := docx.FillDocument(doc.doc, doc.values, placeholders, client)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.name, err)
+		}
+
+		// Two uploads with the same name get numbered entries
+		name := path.Base(doc.name)
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s (%d).docx", strings.TrimSuffix(path.Base(doc.name), ".docx"), i)
+		}
+		names[name] = true
+
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(filled); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
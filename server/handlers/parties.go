@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/parties"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// HandleListParties lists saved parties, optionally filtered by name (?q=acme)
+func HandleListParties(directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.PartiesResponse{
+			Parties: directory.List(c.Query("q")),
+		})
+	}
+}
+
+// HandleCreateParty saves a party for reuse across sessions
+func HandleCreateParty(directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.Party
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: legalName",
+			})
+			return
+		}
+
+		party, err := directory.Create(req)
+		if err != nil {
+			respondPartyError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, party)
+	}
+}
+
+// HandleGetParty returns a saved party
+func HandleGetParty(directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		party, err := directory.Get(c.Param("id"))
+		if err != nil {
+			respondPartyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, party)
+	}
+}
+
+// HandleUpdateParty replaces a saved party's details
+func HandleUpdateParty(directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.Party
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: legalName",
+			})
+			return
+		}
+
+		party, err := directory.Update(c.Param("id"), req)
+		if err != nil {
+			respondPartyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, party)
+	}
+}
+
+// HandleDeleteParty removes a saved party; answers already filled from it stay
+func HandleDeleteParty(directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := directory.Delete(c.Param("id")); err != nil {
+			respondPartyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Party deleted."})
+	}
+}
+
+// HandleBindRole binds a saved or new party to a session role, e.g. "company",
+// filling the role's name, entity type, jurisdiction, address and signatory fields
+func HandleBindRole(store *session.Store, directory *parties.Directory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.BindRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.PartyID == "") == (req.Party == nil) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: partyId or party",
+			})
+			return
+		}
+
+		sessionID := c.Param("id")
+		if _, err := store.Get(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		// A new party is saved to the directory so later sessions can reuse it
+		var party models.Party
+		var err error
+		if req.Party != nil {
+			party, err = directory.Create(*req.Party)
+		} else {
+			party, err = directory.Get(req.PartyID)
+		}
+		if err != nil {
+			respondPartyError(c, err)
+			return
+		}
+
+		role := docx.NormalizeFieldName(c.Param("role"))
+		var resp models.BindRoleResponse
+		var bindErr error
+		err = store.Update(sessionID, func(s *models.Session) {
+			resp, bindErr = session.BindParty(s, role, party, req.Fields, editor(c, "party"))
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to update session.",
+			})
+			return
+		}
+		if bindErr != nil {
+			if req.Party != nil {
+				directory.Delete(party.ID)
+			}
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_binding",
+				Message: bindErr.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// HandleUnbindRole removes a role's party binding; its answers stay
+func HandleUnbindRole(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := docx.NormalizeFieldName(c.Param("role"))
+		err := store.Update(c.Param("id"), func(s *models.Session) {
+			delete(s.Parties, role)
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Role unbound.", "role": role})
+	}
+}
+
+// respondPartyError maps a party directory error to an HTTP response
+func respondPartyError(c *gin.Context, err error) {
+	if errors.Is(err, parties.ErrPartyNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "party_not_found",
+			Message: "Party not found.",
+		})
+		return
+	}
+	if errors.Is(err, parties.ErrInvalidParty) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_party",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "party_error",
+		Message: err.Error(),
+	})
+}
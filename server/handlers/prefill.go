@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/extract"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+)
+
+// HandlePrefill proposes answers from an uploaded .docx/.txt (multipart field
+// "source" or "file") or pasted text ({"text": "..."}). Proposals are held on the
+// session until reviewed; nothing is written to Answers here.
+func HandlePrefill(store *session.Store, ledger *usage.Ledger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+
+		sess, err := store.Get(sessionID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		source, errResp := readPrefillSource(c)
+		if errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+
+		client := meteredClient(ledger, sess.ID, sess.Template, "prefill", ai.ProviderOpenAI, ai.ProviderGemini)
+		proposals, err := extract.FromSource(client, extractFields(sess, false), source)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			c.JSON(http.StatusPaymentRequired, models.ErrorResponse{
+				Error:   "budget_exceeded",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "prefill_failed",
+				Message: "Failed to propose answers: " + err.Error(),
+			})
+			return
+		}
+
+		err = store.Update(sessionID, func(s *models.Session) {
+			for _, p := range proposals {
+				s.Proposals[p.Field] = p
+			}
+			if client != nil {
+				s.PromptVersions[prompts.Prefill] = prompts.Version(prompts.Prefill)
+			}
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to save proposed answers.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.PrefillResponse{
+			Proposals: proposals,
+			Message:   fmt.Sprintf("Proposed answers for %d of %d fields. Review them before they are saved.", len(proposals), len(sess.Fields)),
+		})
+	}
+}
+
+// HandleGetPrefill lists proposals awaiting review
+func HandleGetPrefill(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.PrefillResponse{
+			Proposals: sortedProposals(sess.Proposals),
+			Message:   fmt.Sprintf("%d proposed answers awaiting review.", len(sess.Proposals)),
+		})
+	}
+}
+
+// HandleReviewPrefill writes accepted proposals into Answers and discards rejected ones.
+// An accepted value that isn't valid for its field's type stays proposed and is
+// reported under "invalid".
+func HandleReviewPrefill(store *session.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+
+		var req models.PrefillReviewRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Expected: accept[], reject[]",
+			})
+			return
+		}
+
+		var unknown []string
+		var accepted []string
+		invalid := make(map[string]string)
+		err := store.Update(sessionID, func(s *models.Session) {
+			for _, field := range req.Accept {
+				p, ok := s.Proposals[field]
+				if !ok {
+					unknown = append(unknown, field)
+					continue
+				}
+				value, err := session.NormalizeAnswer(s, field, p.Value)
+				if err != nil {
+					invalid[field] = err.Error()
+					continue
+				}
+				session.SetAnswer(s, field, value, editor(c, "prefill"))
+				delete(s.Proposals, field)
+				accepted = append(accepted, field)
+			}
+			for _, field := range req.Reject {
+				if _, ok := s.Proposals[field]; !ok {
+					unknown = append(unknown, field)
+					continue
+				}
+				delete(s.Proposals, field)
+			}
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		sess, _ := store.Get(sessionID)
+		progress, total := session.Completion(sess)
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Proposals reviewed.",
+			"accepted":  accepted,
+			"invalid":   invalid,
+			"unknown":   unknown,
+			"remaining": sortedProposals(sess.Proposals),
+			"progress":  progress,
+			"total":     total,
+		})
+	}
+}
+
+// readPrefillSource returns source text from a multipart file/text field or a JSON body
+func readPrefillSource(c *gin.Context) (string, *models.ErrorResponse) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("source")
+		if err != nil {
+			file, err = c.FormFile("file")
+		}
+		if err != nil {
+			if text := strings.TrimSpace(c.PostForm("text")); text != "" {
+				return text, nil
+			}
+			return "", &models.ErrorResponse{
+				Error:   "missing_source",
+				Message: "Upload a .docx or .txt file as 'source' or send pasted text as 'text'.",
+			}
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return "", &models.ErrorResponse{Error: "file_read_error", Message: "Failed to read uploaded file."}
+		}
+		defer src.Close()
+
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return "", &models.ErrorResponse{Error: "file_read_error", Message: "Failed to read file contents."}
+		}
+
+		switch strings.ToLower(filepath.Ext(file.Filename)) {
+		case ".txt":
+			return string(data), nil
+		case ".docx":
+			text, err := docx.ExtractText(data)
+			if err != nil {
+				return "", &models.ErrorResponse{Error: "file_read_error", Message: "Failed to read .docx file: " + err.Error()}
+			}
+			return text, nil
+		default:
+			return "", &models.ErrorResponse{Error: "invalid_file_type", Message: "Only .docx and .txt source files are supported."}
+		}
+	}
+
+	var req models.PrefillRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Text) == "" {
+		return "", &models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body. Required: text",
+		}
+	}
+	return req.Text, nil
+}
+
+// sortedProposals returns proposals ordered by field name
+func sortedProposals(proposals map[string]models.AnswerCandidate) []models.AnswerCandidate {
+	list := make([]models.AnswerCandidate, 0, len(proposals))
+	for _, p := range proposals {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Field < list[j].Field })
+	return list
+}
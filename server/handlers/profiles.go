@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/profiles"
+)
+
+// HandleListProfiles returns the document types that can be selected at upload
+func HandleListProfiles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"profiles": profiles.List()})
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+)
+
+// HandleListPrompts returns the loaded prompt templates and their versions
+func HandleListPrompts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"prompts": prompts.List()})
+	}
+}
+
+// HandleReloadPrompts re-reads prompt templates from PROMPTS_DIR without a restart
+func HandleReloadPrompts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := prompts.Reload(); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "prompt_reload_failed",
+				Message: "Failed to reload prompts: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Prompts reloaded successfully.",
+			"prompts": prompts.List(),
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/extract"
+	"github.com/you/lexsy-mvp/server/jobs"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/prompts"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+)
+
+// HandleReverseExtract creates a session from a template and a filled copy of it
+// (multipart fields "template" and "filled"), recovering the answer for every
+// detected field. Values found by diffing are saved as answers; fields the diff
+// can't resolve are proposed by AI for review when a provider is configured.
+// With ?async=true the extraction runs as a background job.
+func HandleReverseExtract(store *session.Store, ledger *usage.Ledger, queue *jobs.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		templateBytes, templateName, errResp := readDocxFormFile(c, "template")
+		if errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		filledBytes, _, errResp := readDocxFormFile(c, "filled")
+		if errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		documentType, ok := documentTypeParam(c)
+		if !ok {
+			return
+		}
+
+		templateText, err := docx.ExtractText(templateBytes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "file_read_error",
+				Message: "Failed to read template: " + err.Error(),
+			})
+			return
+		}
+		filledText, err := docx.ExtractText(filledBytes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "file_read_error",
+				Message: "Failed to read filled document: " + err.Error(),
+			})
+			return
+		}
+
+		sessionID, err := session.NewID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "session_creation_error",
+				Message: "Failed to create session.",
+			})
+			return
+		}
+
+		by := editor(c, "reverse")
+
+		runOrQueue(c, queue, "reverse", sessionID, func(report func(int, string)) jobs.Outcome {
+			// Detect fields in the template exactly as an upload would
+			report(10, "detecting fields")
+			client := meteredClient(ledger, sessionID, templateName, "detect", docx.DetectionProviders...)
+			detection, err := docx.DetectFields(templateBytes, client, documentType)
+			if err != nil {
+				status, errResp := detectionError(err)
+				return jobs.Outcome{Status: status, Body: errResp}
+			}
+			if len(detection.Fields) == 0 {
+				return jobs.Outcome{Status: http.StatusBadRequest, Body: models.ErrorResponse{
+					Error:   "no_fields_found",
+					Message: "No placeholders found in template. Use {{field_name}} format for placeholders.",
+				}}
+			}
+
+			report(40, "recovering answers")
+			answers, unresolved := docx.RecoverAnswers(templateText, filledText, detection.Fields)
+
+			sess, err := createDetectedSession(store, sessionID, templateBytes, templateName, detection, client)
+			if err != nil {
+				return jobs.Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+					Error:   "session_creation_error",
+					Message: "Failed to create session.",
+				}}
+			}
+
+			// Ask AI about fields whose placeholders were renamed or reworded in the template
+			var proposals []models.AnswerCandidate
+			if len(unresolved) > 0 {
+				pending := make([]extract.Field, 0, len(unresolved))
+				store.View(sess.ID, func(s *models.Session) {
+					for _, field := range unresolved {
+						pending = append(pending, extract.Field{Name: field, Type: s.FieldTypes[field], Question: humanizeFieldName(field)})
+					}
+				})
+
+				prefillClient := meteredClient(ledger, sess.ID, templateName, "prefill", ai.ProviderOpenAI, ai.ProviderGemini)
+				if prefillClient != nil {
+					report(60, "proposing unresolved answers")
+					proposals, err = extract.FromSource(prefillClient, pending, filledText)
+					if err != nil && !errors.Is(err, usage.ErrBudgetExceeded) {
+						log.Printf("AI reverse extraction failed, returning diff results only: %v", err)
+					}
+				}
+			}
+
+			// Fields still unresolved after AI proposals
+			proposed := make(map[string]bool, len(proposals))
+			for _, p := range proposals {
+				proposed[p.Field] = true
+			}
+			remaining := []string{}
+			for _, field := range unresolved {
+				if !proposed[field] {
+					remaining = append(remaining, field)
+				}
+			}
+			if proposals == nil {
+				proposals = []models.AnswerCandidate{}
+			}
+
+			// Report the answers as stored, not as they appeared in the filled document
+			report(90, "saving answers")
+			saved := make(map[string]string, len(answers))
+			rejected := make(map[string]string)
+			var response models.ReverseResponse
+			store.Update(sess.ID, func(s *models.Session) {
+				for _, field := range s.Fields {
+					if value, ok := answers[field]; ok {
+						// Filled documents hold rendered values ("$1,000,000"); store them normalized
+						// and leave values that don't fit the field's type to the interview
+						normalized, err := session.NormalizeAnswer(s, field, value)
+						if err != nil {
+							rejected[field] = err.Error()
+							continue
+						}
+						session.SetAnswer(s, field, normalized, by)
+						saved[field] = s.Answers[field]
+					}
+				}
+				for _, p := range proposals {
+					s.Proposals[p.Field] = p
+				}
+				if len(proposals) > 0 {
+					s.PromptVersions[prompts.Prefill] = prompts.Version(prompts.Prefill)
+				}
+				response = models.ReverseResponse{
+					SessionID:    s.ID,
+					OwnerToken:   s.OwnerToken,
+					Fields:       slices.Clone(s.Fields),
+					DocumentType: s.DocumentType,
+					Answers:      saved,
+					Rejected:     rejected,
+					Proposals:    proposals,
+					Unresolved:   remaining,
+					Message:      fmt.Sprintf("Recovered %d of %d fields.", len(saved), len(s.Fields)),
+				}
+			})
+			return jobs.Outcome{Status: http.StatusOK, Body: response}
+		})
+	}
+}
+
+// readDocxFormFile reads a required .docx from a multipart form field
+func readDocxFormFile(c *gin.Context, name string) ([]byte, string, *models.ErrorResponse) {
+	file, err := c.FormFile(name)
+	if err != nil {
+		return nil, "", &models.ErrorResponse{
+			Error:   "missing_file",
+			Message: "No '" + name + "' file uploaded. Please upload a .docx file.",
+		}
+	}
+	return readDocxFile(file)
+}
+
+// readDocxFile reads an uploaded file, which must be a .docx
+func readDocxFile(file *multipart.FileHeader) ([]byte, string, *models.ErrorResponse) {
+	if !strings.HasSuffix(strings.ToLower(file.Filename), ".docx") {
+		return nil, "", &models.ErrorResponse{
+			Error:   "invalid_file_type",
+			Message: "Only .docx files are supported.",
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, "", &models.ErrorResponse{Error: "file_read_error", Message: "Failed to read uploaded file."}
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", &models.ErrorResponse{Error: "file_read_error", Message: "Failed to read file contents."}
+	}
+	return data, file.Filename, nil
+}
@@ -1,16 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/you/lexsy-mvp/server/models"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
 )
 
 // HandleGetSession returns the current session status
-func HandleGetSession(store *session.Store) gin.HandlerFunc {
+func HandleGetSession(store *session.Store, ledger *usage.Ledger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionID := c.Param("id")
 
@@ -23,17 +25,30 @@ func HandleGetSession(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Count answered fields
-		answeredCount := len(sess.Answers)
+		progress, total := session.Completion(sess)
 
 		c.JSON(http.StatusOK, models.SessionStatusResponse{
-			SessionID:   sess.ID,
-			Fields:      sess.Fields,
-			Answers:     sess.Answers,
-			Questions:   sess.Questions,
-			Progress:    answeredCount,
-			Total:       len(sess.Fields),
-			IsCompleted: answeredCount == len(sess.Fields),
+			SessionID:      sess.ID,
+			DocumentType:   sess.DocumentType,
+			Fields:         sess.Fields,
+			FieldGroups:    sess.FieldGroups,
+			FieldTypes:     sess.FieldTypes,
+			Parties:        sess.Parties,
+			Documents:      sess.Documents,
+			TypeReasons:    sess.FieldTypeReasons,
+			Answers:        sess.Answers,
+			Questions:      sess.Questions,
+			Progress:       progress,
+			Total:          total,
+			IsCompleted:    progress == total,
+			Skipped:        sess.Skipped,
+			Optional:       sess.Optional,
+			Conditions:     sess.Conditions,
+			Hidden:         session.Hidden(sess),
+			Computed:       session.ComputedFields(sess),
+			Aliases:        sess.Aliases,
+			Usage:          ledger.SessionTotals(sess.ID),
+			PromptVersions: sess.PromptVersions,
 		})
 	}
 }
@@ -51,6 +66,18 @@ func HandleSubmitAnswers(store *session.Store) gin.HandlerFunc {
 			})
 			return
 		}
+		if req.Answer == "" && len(req.Parts) > 0 {
+			// Structured sub-answers are validated together as one JSON answer
+			parts, _ := json.Marshal(req.Parts)
+			req.Answer = string(parts)
+		}
+		if req.Answer == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: field, answer (or parts)",
+			})
+			return
+		}
 
 		// Check if session exists
 		sess, err := store.Get(sessionID)
@@ -62,26 +89,41 @@ func HandleSubmitAnswers(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Validate field exists
-		fieldExists := false
-		for _, f := range sess.Fields {
-			if f == req.Field {
-				fieldExists = true
-				break
-			}
-		}
-
-		if !fieldExists {
+		// Validate field exists (answers to a merged spelling go to its canonical field)
+		req.Field = session.Canonical(sess, req.Field)
+		if !session.HasField(sess, req.Field) {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error:   "invalid_field",
 				Message: "Field '" + req.Field + "' does not exist in this document.",
 			})
 			return
 		}
+		if session.IsComputed(sess, req.Field) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "computed_field",
+				Message: "Field '" + req.Field + "' is calculated from other answers and can't be answered directly.",
+			})
+			return
+		}
+		if !checkAssigned(c, sess, req.Field) {
+			return
+		}
+
+		// Validate and normalize the answer for the field's type
+		answer, err := session.NormalizeAnswer(sess, req.Field, req.Answer)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_answer",
+				Message: err.Error(),
+			})
+			return
+		}
 
 		// Update session with answer
+		var progress, total int
 		err = store.Update(sessionID, func(s *models.Session) {
-			s.Answers[req.Field] = req.Answer
+			session.SetAnswer(s, req.Field, answer, editor(c, "answer"))
+			progress, total = session.Completion(s)
 		})
 
 		if err != nil {
@@ -95,13 +137,16 @@ func HandleSubmitAnswers(store *session.Store) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "Answer saved successfully.",
 			"field":    req.Field,
-			"progress": len(sess.Answers) + 1,
-			"total":    len(sess.Fields),
+			"answer":   answer,
+			"progress": progress,
+			"total":    total,
 		})
 	}
 }
 
-// HandleGetNextQuestion returns the next unanswered question
+// HandleGetNextQuestion returns the next question to ask. Required fields come first,
+// then optional ones, then skipped fields are revisited. A collaborator is only asked
+// their own fields, and their progress counts only those.
 func HandleGetNextQuestion(store *session.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionID := c.Param("id")
@@ -115,46 +160,62 @@ func HandleGetNextQuestion(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Find first unanswered field
-		for _, field := range sess.Fields {
-			if _, answered := sess.Answers[field]; !answered {
-				// Check if we have an AI-phrased question
-				question, hasAIQuestion := sess.Questions[field]
-				if !hasAIQuestion {
-					// Generate a simple humanized question as fallback
-					question = humanizeFieldName(field)
-				}
-
-				// Get field type (default to "text" if not found)
-				fieldType := "text"
-				if ft, exists := sess.FieldTypes[field]; exists {
-					fieldType = ft
-				}
-
-				c.JSON(http.StatusOK, models.QuestionResponse{
-					Field:       field,
-					FieldType:   fieldType,
-					Question:    question,
-					IsAIPhrased: hasAIQuestion,
-					Progress:    len(sess.Answers),
-					Total:       len(sess.Fields),
-					Done:        false,
-				})
-				return
-			}
+		assigned := session.AssignedFields(sess, requestRole(c))
+		field, ok := session.NextFieldAmong(sess, assigned)
+		if !ok {
+			// All questions answered
+			response := models.QuestionResponse{Done: true}
+			response.Progress, response.Total = session.CompletionAmong(sess, assigned)
+			c.JSON(http.StatusOK, response)
+			return
 		}
 
-		// All questions answered
-		c.JSON(http.StatusOK, models.QuestionResponse{
-			Done:     true,
-			Progress: len(sess.Answers),
-			Total:    len(sess.Fields),
-		})
+		response := questionFor(sess, field)
+		response.Progress, response.Total = session.CompletionAmong(sess, assigned)
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// questionFor builds the question for a field, whether or not it has been answered
+func questionFor(sess *models.Session, field string) models.QuestionResponse {
+	// Check if we have an AI-phrased question
+	question, hasAIQuestion := sess.Questions[field]
+	if !hasAIQuestion {
+		// Generate a simple humanized question as fallback
+		question = humanizeFieldName(field)
+	}
+
+	// Get field type (default to "text" if not found)
+	fieldType := "text"
+	if ft, exists := sess.FieldTypes[field]; exists {
+		fieldType = ft
+	}
+
+	progress, total := session.Completion(sess)
+	return models.QuestionResponse{
+		Field:       field,
+		FieldType:   fieldType,
+		TypeReason:  sess.FieldTypeReasons[field],
+		Options:     session.FieldOptions(sess, field),
+		SubFields:   session.SubFields(sess, field),
+		Group:       sess.FieldGroups[field],
+		Question:    question,
+		IsAIPhrased: hasAIQuestion,
+		Answer:      sess.Answers[field],
+		Skipped:     sess.Skipped[field],
+		Optional:    sess.Optional[field],
+		Progress:    progress,
+		Total:       total,
 	}
 }
 
 // humanizeFieldName converts snake_case to human-readable question
 func humanizeFieldName(field string) string {
+	return "What is the " + fieldLabel(field) + "?"
+}
+
+// fieldLabel converts snake_case to Title Case words
+func fieldLabel(field string) string {
 	// Replace underscores with spaces
 	words := strings.Split(field, "_")
 	for i, word := range words {
@@ -163,5 +224,15 @@ func humanizeFieldName(field string) string {
 			words[i] = strings.ToUpper(string(word[0])) + word[1:]
 		}
 	}
-	return "What is the " + strings.Join(words, " ") + "?"
+	return strings.Join(words, " ")
+}
+
+// editor identifies who is changing answers: the role their token grants, plus the
+// X-Actor header as an unverified display name and the client IP
+func editor(c *gin.Context, source string) session.Editor {
+	actor := strings.TrimSpace(c.GetHeader("X-Actor"))
+	if actor == "" {
+		actor = "anonymous"
+	}
+	return session.Editor{Actor: actor, Role: requestRole(c), IP: c.ClientIP(), Source: source}
 }
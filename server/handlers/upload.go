@@ -8,13 +8,19 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
 	"github.com/you/lexsy-mvp/server/docx"
+	"github.com/you/lexsy-mvp/server/jobs"
 	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/profiles"
+	"github.com/you/lexsy-mvp/server/prompts"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
 )
 
-// HandleUpload processes document upload and creates a new session
-func HandleUpload(store *session.Store) gin.HandlerFunc {
+// HandleUpload processes document upload and creates a new session. With ?async=true
+// detection runs as a background job and the job to poll is returned instead.
+func HandleUpload(store *session.Store, ledger *usage.Ledger, queue *jobs.Queue) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Try to get file from multipart form (try common field names)
 		var file *multipart.FileHeader
@@ -44,6 +50,11 @@ func HandleUpload(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
+		documentType, ok := documentTypeParam(c)
+		if !ok {
+			return
+		}
+
 		// Open and read file
 		src, err := file.Open()
 		if err != nil {
@@ -65,48 +76,125 @@ func HandleUpload(store *session.Store) gin.HandlerFunc {
 			return
 		}
 
-		// Detect placeholders in document
-		fields, err := docx.DetectFields(docBytes)
+		// Reserve the session ID up front so detection usage is attributed to it
+		sessionID, err := session.NewID()
 		if err != nil {
-			// Check if this is a Gemini quota exhaustion error
-			if errors.Is(err, docx.ErrGeminiQuotaExhausted) {
-				c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
-					Error:   "gemini_quota_exhausted",
-					Message: "Gemini free tier quota has been exhausted. Please try again in 2-3 minutes.",
-				})
-				return
-			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "field_detection_error",
-				Message: "Failed to detect fields in document. Error: " + err.Error(),
+				Error:   "session_creation_error",
+				Message: "Failed to create session.",
 			})
 			return
 		}
 
-		// Check if any fields were found
-		if len(fields) == 0 {
+		runOrQueue(c, queue, "upload", sessionID, func(report func(int, string)) jobs.Outcome {
+			// Detect placeholders in document
+			report(10, "detecting fields")
+			client := meteredClient(ledger, sessionID, file.Filename, "detect", docx.DetectionProviders...)
+			detection, err := docx.DetectFields(docBytes, client, documentType)
+			if err != nil {
+				status, errResp := detectionError(err)
+				return jobs.Outcome{Status: status, Body: errResp}
+			}
+
+			// Check if any fields were found
+			fields := detection.Fields
+			if len(fields) == 0 {
+				return jobs.Outcome{Status: http.StatusBadRequest, Body: models.ErrorResponse{
+					Error:   "no_fields_found",
+					Message: "No placeholders found in document. Use {{field_name}} format for placeholders.",
+				}}
+			}
+
+			// Create session
+			report(90, "creating session")
+			sess, err := createDetectedSession(store, sessionID, docBytes, file.Filename, detection, client)
+			if err != nil {
+				return jobs.Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+					Error:   "session_creation_error",
+					Message: "Failed to create session.",
+				}}
+			}
+
+			return jobs.Outcome{Status: http.StatusOK, Body: models.UploadResponse{
+				SessionID:    sess.ID,
+				OwnerToken:   sess.OwnerToken,
+				Fields:       fields,
+				DocumentType: sess.DocumentType,
+				Message:      "Document uploaded successfully.",
+			}}
+		})
+	}
+}
+
+// detectionError maps a DetectFields error to an HTTP status and error body
+func detectionError(err error) (int, models.ErrorResponse) {
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		return http.StatusPaymentRequired, models.ErrorResponse{
+			Error:   "budget_exceeded",
+			Message: err.Error(),
+		}
+	}
+	// Check if this is a Gemini quota exhaustion error
+	if errors.Is(err, docx.ErrGeminiQuotaExhausted) {
+		return http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "gemini_quota_exhausted",
+			Message: "Gemini free tier quota has been exhausted. Please try again in 2-3 minutes.",
+		}
+	}
+	return http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "field_detection_error",
+		Message: "Failed to detect fields in document. Error: " + err.Error(),
+	}
+}
+
+// documentTypeParam reads the optional documentType form field; empty means
+// auto-classify from the document text. An unknown type is answered with a 400.
+func documentTypeParam(c *gin.Context) (string, bool) {
+	documentType := c.PostForm("documentType")
+	if documentType != "" {
+		if _, err := profiles.Get(documentType); err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error:   "no_fields_found",
-				Message: "No placeholders found in document. Use {{field_name}} format for placeholders.",
+				Error:   "invalid_document_type",
+				Message: "Unknown document type '" + documentType + "'. See /api/profiles for supported types.",
 			})
-			return
+			return "", false
 		}
+	}
+	return documentType, true
+}
 
-		// Create session
-		sess, err := store.Create(docBytes, fields)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "session_creation_error",
-				Message: "Failed to create session.",
-			})
-			return
+// createDetectedSession creates a session for a detected template, applying the
+// detection profile's field types and the document text around each placeholder
+func createDetectedSession(store *session.Store, sessionID string, docBytes []byte, filename string, detection *docx.Detection, client ai.Client) (*models.Session, error) {
+	sess, err := store.CreateWithID(sessionID, docBytes, detection.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.Update(sess.ID, func(s *models.Session) {
+		s.Template = filename
+		s.DocumentType = applyDetectedTypes(s, detection).Name
+		if client != nil {
+			s.PromptVersions[prompts.Detection] = prompts.Version(prompts.Detection)
 		}
+		session.Notify(s, models.SessionEvent{Type: session.EventCreated})
+	})
+	return sess, err
+}
 
-		// Return success response
-		c.JSON(http.StatusOK, models.UploadResponse{
-			SessionID: sess.ID,
-			Fields:    fields,
-			Message:   "Document uploaded successfully.",
-		})
+// applyDetectedTypes types a detection's fields from its profile and the document
+// text around each placeholder, returning the profile used
+func applyDetectedTypes(s *models.Session, detection *docx.Detection) *profiles.Profile {
+	profile, err := profiles.Get(detection.DocumentType)
+	if err != nil {
+		profile, _ = profiles.Get(profiles.Generic)
+	}
+
+	for _, field := range detection.Fields {
+		ctx := detection.Contexts[field]
+		inferred := profile.ExplainFieldType(field, ctx.Before, ctx.After)
+		s.FieldTypes[field] = inferred.Type
+		s.FieldTypeReasons[field] = inferred.Reason
 	}
+	return profile
 }
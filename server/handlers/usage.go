@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+)
+
+// HandleGetUsage returns LLM usage totals overall, for today, by provider and by template
+func HandleGetUsage(ledger *usage.Ledger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, ledger.Summary())
+	}
+}
+
+// HandleSetBudget lowers a session's AI spending limit; it can't be raised above the server's
+func HandleSetBudget(store *session.Store, ledger *usage.Ledger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+
+		var req models.BudgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.LimitUSD < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: limitUsd (0 or more)",
+			})
+			return
+		}
+
+		if _, err := store.Get(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found.",
+			})
+			return
+		}
+
+		limit := ledger.SetSessionBudget(sessionID, req.LimitUSD)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Budget updated successfully.",
+			"limitUsd": limit,
+			"usage":    ledger.SessionTotals(sessionID),
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/webhooks"
+)
+
+// HandleListWebhooks lists webhooks; secrets are never shown again after creation
+func HandleListWebhooks(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.WebhooksResponse{
+			Webhooks: dispatcher.List(),
+		})
+	}
+}
+
+// HandleCreateWebhook registers a webhook, globally or for one template, and returns
+// it with its signing secret
+func HandleCreateWebhook(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.Webhook
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: url",
+			})
+			return
+		}
+
+		webhook, err := dispatcher.Create(req)
+		if err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+// HandleGetWebhook returns a webhook
+func HandleGetWebhook(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook, err := dispatcher.Get(c.Param("id"))
+		if err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// HandleUpdateWebhook replaces a webhook's settings
+func HandleUpdateWebhook(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.Webhook
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body. Required: url",
+			})
+			return
+		}
+
+		webhook, err := dispatcher.Update(c.Param("id"), req)
+		if err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// HandleDeleteWebhook removes a webhook; deliveries already being retried still finish
+func HandleDeleteWebhook(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := dispatcher.Delete(c.Param("id")); err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted."})
+	}
+}
+
+// HandleListDeliveries shows a webhook's recent deliveries with every attempt
+func HandleListDeliveries(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deliveries, err := dispatcher.Deliveries(c.Param("id"))
+		if err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, models.DeliveriesResponse{Deliveries: deliveries})
+	}
+}
+
+// HandleTestWebhook sends a signed test event once and reports how the receiver answered
+func HandleTestWebhook(dispatcher *webhooks.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := dispatcher.Test(c.Param("id"))
+		if err != nil {
+			respondWebhookError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// respondWebhookError maps a webhook dispatcher error to an HTTP response
+func respondWebhookError(c *gin.Context, err error) {
+	if errors.Is(err, webhooks.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "webhook_not_found",
+			Message: "Webhook not found.",
+		})
+		return
+	}
+	if errors.Is(err, webhooks.ErrInvalidWebhook) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_webhook",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "webhook_error",
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrQueueFull   = errors.New("job queue is full")
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	defaultWorkers   = 4
+	defaultQueueSize = 100
+	// retention is how long finished jobs can still be polled
+	retention = time.Hour
+)
+
+// Outcome is the response a job produced, as the synchronous endpoint would have sent it
+type Outcome struct {
+	Status      int // HTTP status; 400 and above means the job failed
+	Body        any // JSON result, or a models.ErrorResponse on failure
+	File        []byte
+	FileName    string
+	ContentType string
+}
+
+// Func does a job's work, reporting progress (percent done) and the current stage as it goes
+type Func func(report func(progress int, stage string)) Outcome
+
+// entry is a job with its work and, once finished, its outcome
+type entry struct {
+	job     models.Job
+	work    Func
+	outcome Outcome
+}
+
+// Queue runs jobs on a bounded pool of workers. Jobs run to completion whether or
+// not the client that started them is still connected.
+type Queue struct {
+	mu      sync.RWMutex
+	jobs    map[string]*entry
+	pending chan *entry
+}
+
+// NewQueue starts JOB_WORKERS workers (default 4) taking jobs from a queue of
+// JOB_QUEUE_SIZE (default 100)
+func NewQueue() *Queue {
+	return newQueue(envInt("JOB_WORKERS", defaultWorkers), envInt("JOB_QUEUE_SIZE", defaultQueueSize))
+}
+
+func newQueue(workers, size int) *Queue {
+	q := &Queue{
+		jobs:    make(map[string]*entry),
+		pending: make(chan *entry, size),
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues work, returning the job to poll. Fails with ErrQueueFull when every
+// worker is busy and the queue is full.
+func (q *Queue) Submit(kind, sessionID string, work Func) (models.Job, error) {
+	id, err := session.NewID()
+	if err != nil {
+		return models.Job{}, err
+	}
+	e := &entry{
+		job:  models.Job{ID: id, Kind: kind, SessionID: sessionID, Status: StatusQueued, CreatedAt: time.Now()},
+		work: work,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	select {
+	case q.pending <- e:
+	default:
+		return models.Job{}, ErrQueueFull
+	}
+	q.jobs[id] = e
+	return e.job, nil
+}
+
+// Get returns a copy of a job
+func (q *Queue) Get(id string) (models.Job, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	e, exists := q.jobs[id]
+	if !exists {
+		return models.Job{}, ErrJobNotFound
+	}
+	return e.job, nil
+}
+
+// Outcome returns a finished job's outcome; false while it is still queued or running
+func (q *Queue) Outcome(id string) (Outcome, bool, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	e, exists := q.jobs[id]
+	if !exists {
+		return Outcome{}, false, ErrJobNotFound
+	}
+	return e.outcome, e.job.FinishedAt != nil, nil
+}
+
+// work runs queued jobs one at a time
+func (q *Queue) work() {
+	for e := range q.pending {
+		q.update(e, func(job *models.Job) {
+			now := time.Now()
+			job.Status, job.StartedAt = StatusRunning, &now
+		})
+
+		outcome := q.run(e)
+
+		q.update(e, func(job *models.Job) {
+			now := time.Now()
+			e.outcome = outcome
+			job.FinishedAt = &now
+			job.HTTPStatus = outcome.Status
+			if outcome.Status >= http.StatusBadRequest {
+				job.Status = StatusFailed
+				if errResp, ok := outcome.Body.(models.ErrorResponse); ok {
+					job.Error = &errResp
+				}
+				return
+			}
+			job.Status, job.Progress, job.Stage = StatusSucceeded, 100, ""
+			if outcome.File != nil {
+				job.DownloadURL = "/api/jobs/" + job.ID + "/download"
+			} else {
+				job.Result = outcome.Body
+			}
+		})
+	}
+}
+
+// run does a job's work; a panic fails the job instead of killing the worker
+func (q *Queue) run(e *entry) (outcome Outcome) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v", e.job.ID, r)
+			outcome = Outcome{Status: http.StatusInternalServerError, Body: models.ErrorResponse{
+				Error:   "job_failed",
+				Message: "The job failed unexpectedly.",
+			}}
+		}
+	}()
+
+	return e.work(func(progress int, stage string) {
+		q.update(e, func(job *models.Job) {
+			job.Progress, job.Stage = progress, stage
+		})
+	})
+}
+
+// update changes a job under the lock
+func (q *Queue) update(e *entry, fn func(job *models.Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fn(&e.job)
+}
+
+// prune forgets jobs that finished more than the retention period ago
+func (q *Queue) prune() {
+	for id, e := range q.jobs {
+		if e.job.FinishedAt != nil && time.Since(*e.job.FinishedAt) > retention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func envInt(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		log.Printf("Ignoring invalid %s: %q", name, raw)
+		return fallback
+	}
+	return v
+}
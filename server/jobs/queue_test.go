@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// wait polls a job until it finishes
+func wait(t *testing.T, q *Queue, id string) models.Job {
+	for i := 0; i < 200; i++ {
+		job, err := q.Get(id)
+		require.NoError(t, err)
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return models.Job{}
+}
+
+// TestQueueRunsJobsOnBoundedPool tests progress, results, back-pressure and failures
+func TestQueueRunsJobsOnBoundedPool(t *testing.T) {
+	q := newQueue(1, 1)
+
+	// The only worker blocks on the first job; the second waits in the queue; a third is refused
+	release := make(chan struct{})
+	first, err := q.Submit("upload", "s1", func(report func(int, string)) Outcome {
+		report(50, "detecting fields")
+		<-release
+		return Outcome{Status: http.StatusOK, Body: map[string]string{"sessionId": "s1"}}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, first.Status)
+
+	require.Eventually(t, func() bool {
+		job, _ := q.Get(first.ID)
+		return job.Progress == 50
+	}, time.Second, 5*time.Millisecond)
+	running, _ := q.Get(first.ID)
+	assert.Equal(t, StatusRunning, running.Status)
+	assert.Equal(t, "detecting fields", running.Stage)
+
+	second, err := q.Submit("generate", "s1", func(func(int, string)) Outcome {
+		return Outcome{Status: http.StatusOK, File: []byte("docx"), FileName: "filled_document.docx"}
+	})
+	require.NoError(t, err)
+	_, err = q.Submit("generate", "s1", func(func(int, string)) Outcome { return Outcome{} })
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	close(release)
+	done := wait(t, q, first.ID)
+	assert.Equal(t, StatusSucceeded, done.Status)
+	assert.Equal(t, 100, done.Progress)
+	assert.Equal(t, map[string]string{"sessionId": "s1"}, done.Result)
+
+	done = wait(t, q, second.ID)
+	assert.Equal(t, "/api/jobs/"+second.ID+"/download", done.DownloadURL)
+	outcome, finished, err := q.Outcome(second.ID)
+	require.NoError(t, err)
+	assert.True(t, finished)
+	assert.Equal(t, []byte("docx"), outcome.File)
+
+	// A failing or panicking job reports its error and leaves the worker running
+	failed, err := q.Submit("upload", "s2", func(func(int, string)) Outcome {
+		return Outcome{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "no_fields_found"}}
+	})
+	require.NoError(t, err)
+	done = wait(t, q, failed.ID)
+	assert.Equal(t, StatusFailed, done.Status)
+	assert.Equal(t, http.StatusBadRequest, done.HTTPStatus)
+	assert.Equal(t, "no_fields_found", done.Error.Error)
+
+	panicked, err := q.Submit("upload", "s3", func(func(int, string)) Outcome { panic("boom") })
+	require.NoError(t, err)
+	assert.Equal(t, "job_failed", wait(t, q, panicked.ID).Error.Error)
+
+	_, err = q.Get("missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
@@ -7,8 +7,15 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/you/lexsy-mvp/server/ai"
 	"github.com/you/lexsy-mvp/server/handlers"
+	"github.com/you/lexsy-mvp/server/jobs"
+	"github.com/you/lexsy-mvp/server/parties"
+	"github.com/you/lexsy-mvp/server/prompts"
 	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/usage"
+	"github.com/you/lexsy-mvp/server/utils"
+	"github.com/you/lexsy-mvp/server/webhooks"
 )
 
 func main() {
@@ -19,8 +26,14 @@ func main() {
 
 	r := gin.Default() // Includes Logger and Recovery middleware
 
-	// Initialize session store
+	// Initialize session store, AI usage ledger, party directory, background job queue
+	// and webhook dispatcher
 	store := session.NewStore()
+	ledger := usage.NewLedger()
+	directory := parties.NewDirectory()
+	queue := jobs.NewQueue()
+	dispatcher := webhooks.NewDispatcher()
+	store.Listen(dispatcher.SessionListener)
 
 	// CORS configuration
 	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
@@ -33,11 +46,22 @@ func main() {
 		origins[i] = strings.TrimSpace(origin)
 	}
 	log.Printf("CORS allowed origins: %v", origins)
+	log.Printf("AI provider: %s", ai.ActiveProvider(ai.ProviderGemini, ai.ProviderOpenAI))
+
+	// Load prompt templates (embedded defaults, overridden from PROMPTS_DIR)
+	if err := prompts.Reload(); err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+
+	// Extra field type keywords, e.g. {"currency": ["retainer"]}
+	if err := utils.LoadTypeDictionary(os.Getenv("FIELD_TYPE_DICTIONARY")); err != nil {
+		log.Fatalf("Failed to load field type dictionary: %v", err)
+	}
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     origins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-Actor", "X-Session-Token"},
 		ExposeHeaders:    []string{"Content-Disposition"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -45,18 +69,72 @@ func main() {
 
 	// Health check
 	r.GET("/api/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		c.JSON(200, gin.H{
+			"status":     "ok",
+			"aiProvider": ai.ActiveProvider(ai.ProviderGemini, ai.ProviderOpenAI),
+		})
 	})
 
 	// API routes
 	api := r.Group("/api")
+	api.Use(handlers.SessionAccess(store))
 	{
-		api.POST("/upload", handlers.HandleUpload(store))
-		api.GET("/session/:id", handlers.HandleGetSession(store))
+		api.POST("/upload", handlers.HandleUpload(store, ledger, queue))
+		api.POST("/reverse", handlers.HandleReverseExtract(store, ledger, queue))
+		api.POST("/packages", handlers.HandleUploadPackage(store, ledger, queue))
+		api.GET("/session/:id", handlers.HandleGetSession(store, ledger))
 		api.POST("/session/:id/answers", handlers.HandleSubmitAnswers(store))
 		api.GET("/session/:id/next", handlers.HandleGetNextQuestion(store))
-		api.POST("/session/:id/ai/questions", handlers.HandleGenerateQuestions(store))
-		api.POST("/session/:id/generate", handlers.HandleGenerateDocument(store))
+		api.DELETE("/session/:id/answers/:field", handlers.HandleClearAnswer(store))
+		api.POST("/session/:id/skip", handlers.HandleSkipField(store))
+		api.POST("/session/:id/fields", handlers.HandleAddField(store))
+		api.PATCH("/session/:id/fields/:field", handlers.HandleUpdateField(store))
+		api.DELETE("/session/:id/fields/:field", handlers.HandleRemoveField(store))
+		api.PUT("/session/:id/fields/:field/optional", handlers.HandleSetOptional(store))
+		api.GET("/session/:id/questions/:field", handlers.HandleGetQuestion(store))
+		api.PUT("/session/:id/fields/:field/condition", handlers.HandleSetCondition(store))
+		api.DELETE("/session/:id/fields/:field/condition", handlers.HandleDeleteCondition(store))
+		api.PUT("/session/:id/fields/:field/computed", handlers.HandleSetComputed(store))
+		api.DELETE("/session/:id/fields/:field/computed", handlers.HandleDeleteComputed(store))
+		api.GET("/session/:id/merges", handlers.HandleGetMergeSuggestions(store))
+		api.POST("/session/:id/merge", handlers.HandleMergeFields(store))
+		api.POST("/session/:id/split", handlers.HandleSplitField(store))
+		api.PUT("/session/:id/roles/:role", handlers.HandleBindRole(store, directory))
+		api.DELETE("/session/:id/roles/:role", handlers.HandleUnbindRole(store))
+		api.POST("/session/:id/collaborators", handlers.HandleSetCollaborator(store))
+		api.GET("/session/:id/collaborators", handlers.HandleListCollaborators(store))
+		api.DELETE("/session/:id/collaborators/:role", handlers.HandleRemoveCollaborator(store))
+		api.GET("/parties", handlers.HandleListParties(directory))
+		api.POST("/parties", handlers.HandleCreateParty(directory))
+		api.GET("/parties/:id", handlers.HandleGetParty(directory))
+		api.PUT("/parties/:id", handlers.HandleUpdateParty(directory))
+		api.DELETE("/parties/:id", handlers.HandleDeleteParty(directory))
+		api.GET("/session/:id/history", handlers.HandleGetHistory(store))
+		api.GET("/session/:id/events", handlers.HandleSessionEvents(store))
+		api.POST("/session/:id/undo", handlers.HandleUndo(store))
+		api.POST("/session/:id/revert", handlers.HandleRevert(store))
+		api.POST("/session/:id/message", handlers.HandleMessage(store, ledger))
+		api.POST("/session/:id/prefill", handlers.HandlePrefill(store, ledger))
+		api.GET("/session/:id/prefill", handlers.HandleGetPrefill(store))
+		api.POST("/session/:id/prefill/review", handlers.HandleReviewPrefill(store))
+		api.POST("/session/:id/ai/questions", handlers.HandleGenerateQuestions(store, ledger))
+		api.PUT("/session/:id/budget", handlers.HandleSetBudget(store, ledger))
+		api.POST("/session/:id/generate", handlers.HandleGenerateDocument(store, ledger, queue))
+		api.GET("/jobs/:id", handlers.HandleGetJob(queue))
+		api.GET("/jobs/:id/download", handlers.HandleDownloadJob(queue))
+		adminAccess := handlers.AdminAccess(os.Getenv("ADMIN_TOKEN"))
+		admin := api.Group("/webhooks", adminAccess)
+		admin.GET("", handlers.HandleListWebhooks(dispatcher))
+		admin.POST("", handlers.HandleCreateWebhook(dispatcher))
+		admin.GET("/:id", handlers.HandleGetWebhook(dispatcher))
+		admin.PUT("/:id", handlers.HandleUpdateWebhook(dispatcher))
+		admin.DELETE("/:id", handlers.HandleDeleteWebhook(dispatcher))
+		admin.GET("/:id/deliveries", handlers.HandleListDeliveries(dispatcher))
+		admin.POST("/:id/test", handlers.HandleTestWebhook(dispatcher))
+		api.GET("/usage", handlers.HandleGetUsage(ledger))
+		api.GET("/profiles", handlers.HandleListProfiles())
+		api.GET("/prompts", handlers.HandleListPrompts())
+		api.POST("/prompts/reload", adminAccess, handlers.HandleReloadPrompts())
 	}
 
 	// Start server
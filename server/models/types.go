@@ -4,56 +4,478 @@ import "time"
 
 // Session represents a document filling session
 type Session struct {
-	ID          string            `json:"id"`
-	OriginalDoc []byte            `json:"-"` // Raw DOCX bytes (not sent to client)
-	Fields      []string          `json:"fields"`
-	FieldTypes  map[string]string `json:"fieldTypes"` // field -> type (text, number, date)
+	ID           string `json:"id"`
+	Template     string `json:"template"`     // Uploaded file name
+	DocumentType string `json:"documentType"` // Detection profile (safe, nda, lease, ...)
+	OriginalDoc  []byte `json:"-"`            // Raw DOCX bytes (not sent to client)
+	// Documents of a package sharing this interview; empty for single-document sessions
+	Documents  []Document        `json:"documents"`
+	Fields     []string          `json:"fields"`
+	FieldTypes map[string]string `json:"fieldTypes"` // field -> type (text, currency, date, ...)
+	// Why each field has its type, e.g. `preceded by "$" in the document` (field -> reason)
+	FieldTypeReasons map[string]string `json:"fieldTypeReasons"`
+	// Choices for select fields, or to override a type's defaults (field -> options)
+	FieldOptions map[string][]string `json:"fieldOptions"`
+	// How answers are written into the document (field -> format, e.g. multi_line for addresses)
+	Formats map[string]string `json:"formats"`
+	// field -> interview section (parties, economics, dates, signatures, other)
+	FieldGroups map[string]string `json:"fieldGroups"`
 	Answers     map[string]string `json:"answers"`
 	Questions   map[string]string `json:"questions"` // AI-phrased questions (field -> question)
-	CreatedAt   time.Time         `json:"createdAt"`
-	UpdatedAt   time.Time         `json:"updatedAt"`
+	// Prompt template versions used for this session (prompt name -> version)
+	PromptVersions map[string]string `json:"promptVersions"`
+	// Answers proposed from a source document, awaiting review (field -> proposal)
+	Proposals map[string]AnswerCandidate `json:"proposals"`
+	// Every answer change in order; never rewritten, reverts are appended as new events
+	History []AnswerEvent `json:"history"`
+	// Events queued during an update, published to watchers once it finishes
+	PendingEvents []SessionEvent  `json:"-"`
+	Skipped       map[string]bool `json:"skipped"`  // Fields deferred to revisit later
+	Optional      map[string]bool `json:"optional"` // Fields that may be left blank
+	// Fields that are only asked when a condition on another answer holds
+	Conditions map[string]Condition `json:"conditions"`
+	// Fields calculated from other answers instead of asked (field -> expression)
+	Computed map[string]string `json:"computed"`
+	// Exact placeholder text for fields added by hand (field -> placeholder)
+	Placeholders map[string]string `json:"placeholders"`
+	// Placeholder spellings merged into another field (alias -> canonical field)
+	Aliases map[string]string `json:"aliases"`
+	// Secret for full access once collaborators are invited (returned on upload only)
+	OwnerToken string `json:"-"`
+	// Roles invited to answer some of the fields (role -> collaborator)
+	Collaborators map[string]Collaborator `json:"collaborators"`
+	// Parties bound to roles such as "company" or "investor" (role -> party ID)
+	Parties   map[string]string `json:"parties"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// Document is one template in a multi-document package
+type Document struct {
+	Name         string   `json:"name"`         // Uploaded file name
+	DocumentType string   `json:"documentType"` // Detection profile
+	Fields       []string `json:"fields"`       // Placeholders detected in this document
+	OriginalDoc  []byte   `json:"-"`
+}
+
+// Collaborator is a role invited to answer its assigned fields through its own token
+type Collaborator struct {
+	Role      string    `json:"role"`
+	Fields    []string  `json:"fields"`
+	Token     string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AnswerEvent records one change to an answer for the audit trail
+type AnswerEvent struct {
+	ID        int       `json:"id"` // Sequence number within the session, starting at 1
+	Field     string    `json:"field"`
+	OldValue  *string   `json:"oldValue"` // nil when the field had no answer
+	NewValue  *string   `json:"newValue"` // nil when the answer was cleared
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"`            // Name the client gave (X-Actor header), for display only
+	Role      string    `json:"role"`             // Role the request's token granted: owner or a collaborator's
+	IP        string    `json:"ip"`               // Client IP of the request
+	Source    string    `json:"source"`           // answer, message, prefill, reverse, revert, ...
+	ChangeSet int       `json:"changeSet"`        // ID of the first event of the request that made this change
+	Undoes    int       `json:"undoes,omitempty"` // Change set this event undid
+}
+
+// SessionEvent is a change pushed to clients watching a session
+type SessionEvent struct {
+	Type     string    `json:"type"`             // ready, answer, questions or generation
+	Field    string    `json:"field,omitempty"`  // answer: the field that changed
+	Answer   *string   `json:"answer,omitempty"` // answer: the new value, absent when cleared
+	Actor    string    `json:"actor,omitempty"`
+	Role     string    `json:"role,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Status   string    `json:"status,omitempty"` // generation: started, completed or failed
+	Count    int       `json:"count,omitempty"`  // questions: how many were phrased
+	Progress int       `json:"progress"`
+	Total    int       `json:"total"`
+	At       time.Time `json:"at"`
+}
+
+// Job is slow work (detection, generation) run in the background; poll it with GET /api/jobs/:id
+type Job struct {
+	ID          string         `json:"id"`
+	Kind        string         `json:"kind"` // upload, package, reverse or generate
+	SessionID   string         `json:"sessionId,omitempty"`
+	Status      string         `json:"status"`   // queued, running, succeeded or failed
+	Progress    int            `json:"progress"` // Percent done
+	Stage       string         `json:"stage,omitempty"`
+	Result      any            `json:"result,omitempty"` // What the synchronous endpoint would have returned
+	Error       *ErrorResponse `json:"error,omitempty"`
+	HTTPStatus  int            `json:"httpStatus,omitempty"`  // Status the synchronous endpoint would have used
+	DownloadURL string         `json:"downloadUrl,omitempty"` // Set when the result is a file
+	CreatedAt   time.Time      `json:"createdAt"`
+	StartedAt   *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// Webhook posts session lifecycle events to an external system
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url" binding:"required"`
+	Events    []string  `json:"events"`             // session.created, session.completed, session.generated; empty means all
+	Template  string    `json:"template,omitempty"` // Only sessions for this template file name or document type; empty means all
+	Secret    string    `json:"secret,omitempty"`   // HMAC signing key, only shown when the webhook is created
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhooksResponse lists webhooks
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID        string          `json:"id"` // Delivery ID, the same on every retry
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Session   *WebhookSession `json:"session,omitempty"` // Absent for test deliveries
+}
+
+// WebhookSession is the session as it was when the event happened
+type WebhookSession struct {
+	SessionID    string            `json:"sessionId"`
+	Template     string            `json:"template"`
+	DocumentType string            `json:"documentType"`
+	Answers      map[string]string `json:"answers"`
+	Progress     int               `json:"progress"`
+	Total        int               `json:"total"`
+	IsCompleted  bool              `json:"isCompleted"`
+}
+
+// WebhookDelivery is the log of one event sent to a webhook, with every attempt
+type WebhookDelivery struct {
+	ID        string            `json:"id"`
+	WebhookID string            `json:"webhookId"`
+	Event     string            `json:"event"`
+	SessionID string            `json:"sessionId,omitempty"`
+	Status    string            `json:"status"` // pending, succeeded or failed
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// DeliveryAttempt is one try at posting a delivery
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// DeliveriesResponse lists a webhook's recent deliveries, newest first
+type DeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
 }
 
 // UploadResponse is returned after a successful document upload
 type UploadResponse struct {
-	SessionID string   `json:"sessionId"`
-	Fields    []string `json:"fields"`
-	Message   string   `json:"message"`
+	SessionID    string   `json:"sessionId"`
+	OwnerToken   string   `json:"ownerToken"` // Keeps full access once collaborators are invited
+	Fields       []string `json:"fields"`
+	DocumentType string   `json:"documentType"` // Detection profile used (chosen or auto-classified)
+	Message      string   `json:"message"`
+}
+
+// PackageResponse is returned after uploading several documents as one package
+type PackageResponse struct {
+	SessionID  string            `json:"sessionId"`
+	OwnerToken string            `json:"ownerToken"`
+	Documents  []Document        `json:"documents"`
+	Fields     []string          `json:"fields"` // Union of the documents' fields, after merging
+	Merged     []MergeSuggestion `json:"merged"` // Fields merged across documents
+	Message    string            `json:"message"`
 }
 
 // QuestionResponse is returned when requesting the next question
 type QuestionResponse struct {
-	Field       string `json:"field"`
-	FieldType   string `json:"fieldType"`   // Type: text, number, or date
-	Question    string `json:"question"`
-	IsAIPhrased bool   `json:"isAIPhrased"` // True if AI-generated, false if fallback
-	Progress    int    `json:"progress"`    // Number of answered fields
-	Total       int    `json:"total"`       // Total number of fields
-	Done        bool   `json:"done"`        // True if all questions answered
+	Field       string     `json:"field"`
+	FieldType   string     `json:"fieldType"`           // Type: text, number, date, currency, percent, email, ...
+	TypeReason  string     `json:"typeReason"`          // Why the field has this type
+	Options     []string   `json:"options,omitempty"`   // Choices for select, boolean and entity type fields
+	SubFields   []SubField `json:"subFields,omitempty"` // Parts collected separately, e.g. an address's city
+	Group       string     `json:"group"`               // Section: parties, economics, dates, signatures, other
+	Question    string     `json:"question"`
+	IsAIPhrased bool       `json:"isAIPhrased"`      // True if AI-generated, false if fallback
+	Answer      string     `json:"answer,omitempty"` // Current answer when revisiting a field
+	Skipped     bool       `json:"skipped"`          // Field was deferred earlier
+	Optional    bool       `json:"optional"`         // Field may be left blank
+	Progress    int        `json:"progress"`         // Number of answered required fields
+	Total       int        `json:"total"`            // Number of required fields
+	Done        bool       `json:"done"`             // True if all questions answered
 }
 
 // AnswerRequest is the request body for submitting answers
 type AnswerRequest struct {
 	Field  string `json:"field" binding:"required"`
-	Answer string `json:"answer" binding:"required"`
+	Answer string `json:"answer"`
+	// Sub-answers for structured fields instead of answer, e.g. {"street": ..., "city": ...}
+	Parts map[string]string `json:"parts"`
+}
+
+// SubField is one part of a structured answer
+type SubField struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
 }
 
 // GenerateQuestionsResponse is returned after AI question generation
 type GenerateQuestionsResponse struct {
 	Questions map[string]string `json:"questions"` // field -> AI-phrased question
 	Count     int               `json:"count"`
+	Provider  string            `json:"provider"` // AI provider used, or "none" for the fallback
 	Message   string            `json:"message"`
 }
 
 // SessionStatusResponse returns the current session status
 type SessionStatusResponse struct {
-	SessionID   string            `json:"sessionId"`
-	Fields      []string          `json:"fields"`
-	Answers     map[string]string `json:"answers"`
-	Questions   map[string]string `json:"questions"`
-	Progress    int               `json:"progress"`
-	Total       int               `json:"total"`
-	IsCompleted bool              `json:"isCompleted"`
+	SessionID    string                   `json:"sessionId"`
+	DocumentType string                   `json:"documentType"`
+	Fields       []string                 `json:"fields"`      // Interview order
+	FieldGroups  map[string]string        `json:"fieldGroups"` // field -> section
+	FieldTypes   map[string]string        `json:"fieldTypes"`
+	TypeReasons  map[string]string        `json:"typeReasons"` // Why each field has its type
+	Answers      map[string]string        `json:"answers"`
+	Questions    map[string]string        `json:"questions"`
+	Progress     int                      `json:"progress"`
+	Total        int                      `json:"total"`
+	IsCompleted  bool                     `json:"isCompleted"` // All required fields answered
+	Skipped      map[string]bool          `json:"skipped"`
+	Optional     map[string]bool          `json:"optional"`
+	Conditions   map[string]Condition     `json:"conditions"`
+	Hidden       []string                 `json:"hidden"`              // Fields whose condition doesn't hold
+	Computed     map[string]ComputedField `json:"computed"`            // Read-only calculated values
+	Aliases      map[string]string        `json:"aliases"`             // Merged placeholder spellings (alias -> field)
+	Parties      map[string]string        `json:"parties"`             // Bound parties (role -> party ID)
+	Documents    []Document               `json:"documents,omitempty"` // Package documents
+	Usage        UsageTotals              `json:"usage"`               // LLM usage attributed to this session
+	// Prompt template versions used for this session (prompt name -> version)
+	PromptVersions map[string]string `json:"promptVersions"`
+}
+
+// MessageRequest is a free-text chat reply that may answer several fields
+type MessageRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// AnswerCandidate is a value extracted for a field
+type AnswerCandidate struct {
+	Field      string  `json:"field"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`         // 0-1
+	FollowUp   string  `json:"followUp,omitempty"` // Clarifying question when confidence is low
+	Snippet    string  `json:"snippet,omitempty"`  // Source passage the value came from
+}
+
+// MessageResponse reports which answers were saved from a chat message
+type MessageResponse struct {
+	Saved     []AnswerCandidate `json:"saved"`     // Confident values written to the session
+	FollowUps []AnswerCandidate `json:"followUps"` // Ambiguous values that need confirmation
+	Progress  int               `json:"progress"`
+	Total     int               `json:"total"`
+	Done      bool              `json:"done"`
+}
+
+// PrefillRequest carries pasted source text (files are sent as multipart instead)
+type PrefillRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// PrefillResponse lists answers proposed from a source document
+type PrefillResponse struct {
+	Proposals []AnswerCandidate `json:"proposals"`
+	Message   string            `json:"message"`
+}
+
+// PrefillReviewRequest accepts or rejects proposed answers by field
+type PrefillReviewRequest struct {
+	Accept []string `json:"accept"`
+	Reject []string `json:"reject"`
+}
+
+// ReverseResponse is returned after recovering answers from a filled document
+type ReverseResponse struct {
+	SessionID    string            `json:"sessionId"`
+	OwnerToken   string            `json:"ownerToken"`
+	Fields       []string          `json:"fields"`
+	DocumentType string            `json:"documentType"`
+	Answers      map[string]string `json:"answers"`    // Values recovered by diffing against the template, as saved
+	Rejected     map[string]string `json:"rejected"`   // field -> why the recovered value didn't fit its type
+	Proposals    []AnswerCandidate `json:"proposals"`  // AI-suggested values awaiting review
+	Unresolved   []string          `json:"unresolved"` // Fields with no recovered value
+	Message      string            `json:"message"`
+}
+
+// Condition makes a field depend on another field's answer.
+// Op is one of: equals, not_equals, in, answered, not_answered. Comparisons ignore case.
+type Condition struct {
+	Field  string   `json:"field" binding:"required"`
+	Op     string   `json:"op" binding:"required"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"` // For "in"
+}
+
+// ComputedRequest defines a computed field, e.g. "valuation_cap + purchase_amount"
+type ComputedRequest struct {
+	Expression string `json:"expression" binding:"required"`
+}
+
+// ComputedField is a computed field's expression with its current value, or why it can't be calculated yet
+type ComputedField struct {
+	Expression string `json:"expression"`
+	Value      string `json:"value,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MergeRequest folds alias fields into a canonical field
+type MergeRequest struct {
+	Canonical string   `json:"canonical" binding:"required"`
+	Aliases   []string `json:"aliases" binding:"required"`
+}
+
+// MergeSuggestion proposes fields that look like the same thing
+type MergeSuggestion struct {
+	Canonical string   `json:"canonical"`
+	Aliases   []string `json:"aliases"`
+	Reason    string   `json:"reason"`
+}
+
+// MergeSuggestionsResponse lists likely duplicate fields
+type MergeSuggestionsResponse struct {
+	Suggestions []MergeSuggestion `json:"suggestions"`
+}
+
+// AddFieldRequest adds a field the detector missed
+type AddFieldRequest struct {
+	Field       string   `json:"field" binding:"required"` // Key, normalized to snake_case
+	Placeholder string   `json:"placeholder"`              // Exact text in the document, e.g. "[Governing State]"
+	Type        string   `json:"type"`                     // Defaults to the document type's inference
+	Options     []string `json:"options"`                  // Choices for select fields
+}
+
+// UpdateFieldRequest renames a field and/or changes its type or format
+type UpdateFieldRequest struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options"` // Choices for select fields
+	Format  string   `json:"format"`  // Address layout: single_line or multi_line
+}
+
+// FieldRequest names a field to act on
+type FieldRequest struct {
+	Field string `json:"field" binding:"required"`
+}
+
+// OptionalRequest marks a field as optional or required
+type OptionalRequest struct {
+	Optional bool `json:"optional"`
+}
+
+// Party is a person or entity kept once and bound to roles in any number of sessions
+type Party struct {
+	ID             string    `json:"id"`
+	LegalName      string    `json:"legalName"`
+	EntityType     string    `json:"entityType,omitempty"`   // corporation, limited liability company, ...
+	Jurisdiction   string    `json:"jurisdiction,omitempty"` // e.g. Delaware
+	Address        string    `json:"address,omitempty"`      // Single line, e.g. "1 Main St, Springfield, IL 62701"
+	SignatoryName  string    `json:"signatoryName,omitempty"`
+	SignatoryTitle string    `json:"signatoryTitle,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// PartiesResponse lists saved parties
+type PartiesResponse struct {
+	Parties []Party `json:"parties"`
+}
+
+// BindRoleRequest binds a saved party, or a new one, to a session role.
+// Fields overrides which party attribute fills a field (field -> attribute).
+type BindRoleRequest struct {
+	PartyID string            `json:"partyId"`
+	Party   *Party            `json:"party"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// BindRoleResponse reports the answers filled from a party
+type BindRoleResponse struct {
+	Role      string            `json:"role"`
+	Party     Party             `json:"party"`
+	Filled    map[string]string `json:"filled"`    // field -> saved answer
+	Rejected  map[string]string `json:"rejected"`  // field -> why the party's value didn't fit
+	Unmatched []string          `json:"unmatched"` // Attributes with a value but no field for this role
+}
+
+// CollaboratorRequest assigns fields to a role
+type CollaboratorRequest struct {
+	Role   string   `json:"role" binding:"required"`
+	Fields []string `json:"fields" binding:"required"`
+}
+
+// CollaboratorResponse is a role's assignment with its access token and link
+type CollaboratorResponse struct {
+	Role   string   `json:"role"`
+	Fields []string `json:"fields"`
+	Token  string   `json:"token"` // Send as X-Session-Token or ?token=
+	Link   string   `json:"link"`  // Interview page for this role
+}
+
+// CollaboratorProgress is how far a role has got with its fields
+type CollaboratorProgress struct {
+	Role     string   `json:"role"`
+	Fields   []string `json:"fields"`
+	Answered int      `json:"answered"`
+	Total    int      `json:"total"`
+	Done     bool     `json:"done"`
+}
+
+// CollaboratorsResponse gives the owner each role's progress and the session's overall progress
+type CollaboratorsResponse struct {
+	Collaborators []CollaboratorProgress `json:"collaborators"`
+	Unassigned    []string               `json:"unassigned"` // Fields no role answers, left to the owner
+	Progress      int                    `json:"progress"`
+	Total         int                    `json:"total"`
+	IsCompleted   bool                   `json:"isCompleted"`
+}
+
+// HistoryResponse lists answer events for a session
+type HistoryResponse struct {
+	SessionID string        `json:"sessionId"`
+	Events    []AnswerEvent `json:"events"`
+}
+
+// RevertRequest restores answers to how they were right after an event (0 = before any answers)
+type RevertRequest struct {
+	EventID *int `json:"eventId" binding:"required"`
+}
+
+// UsageTotals aggregates LLM usage and estimated cost
+type UsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// UsageResponse is returned by the usage endpoint
+type UsageResponse struct {
+	Total            UsageTotals            `json:"total"`
+	Today            UsageTotals            `json:"today"`
+	ByProvider       map[string]UsageTotals `json:"byProvider"`
+	ByTemplate       map[string]UsageTotals `json:"byTemplate"`
+	SessionBudgetUSD float64                `json:"sessionBudgetUsd,omitempty"` // 0 means unlimited
+	DailyBudgetUSD   float64                `json:"dailyBudgetUsd,omitempty"`   // 0 means unlimited
+}
+
+// BudgetRequest sets a per-session AI spending limit
+type BudgetRequest struct {
+	LimitUSD float64 `json:"limitUsd"` // 0 removes the session override
 }
 
 // ErrorResponse is a standard error response
@@ -0,0 +1,150 @@
+package parties
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+var (
+	ErrPartyNotFound = errors.New("party not found")
+	ErrInvalidParty  = errors.New("invalid party")
+)
+
+// Directory is a thread-safe in-memory store of parties shared by all sessions
+type Directory struct {
+	mu      sync.RWMutex
+	parties map[string]*models.Party
+}
+
+// NewDirectory creates an empty party directory
+func NewDirectory() *Directory {
+	return &Directory{
+		parties: make(map[string]*models.Party),
+	}
+}
+
+// Create validates and saves a new party, returning the stored copy
+func (d *Directory) Create(p models.Party) (models.Party, error) {
+	p, err := normalize(p)
+	if err != nil {
+		return models.Party{}, err
+	}
+	if p.ID, err = session.NewID(); err != nil {
+		return models.Party{}, err
+	}
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	d.mu.Lock()
+	d.parties[p.ID] = &p
+	d.mu.Unlock()
+
+	return p, nil
+}
+
+// Get returns a copy of a party by ID
+func (d *Directory) Get(id string) (models.Party, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	p, exists := d.parties[id]
+	if !exists {
+		return models.Party{}, ErrPartyNotFound
+	}
+	return *p, nil
+}
+
+// List returns parties whose legal name contains query (all when empty), sorted by name
+func (d *Directory) List(query string) []models.Party {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	list := make([]models.Party, 0, len(d.parties))
+	for _, p := range d.parties {
+		if strings.Contains(strings.ToLower(p.LegalName), query) {
+			list = append(list, *p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].LegalName) < strings.ToLower(list[j].LegalName)
+	})
+	return list
+}
+
+// Update replaces a party's details. Sessions it was bound to keep the answers
+// they were filled with.
+func (d *Directory) Update(id string, p models.Party) (models.Party, error) {
+	p, err := normalize(p)
+	if err != nil {
+		return models.Party{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	existing, exists := d.parties[id]
+	if !exists {
+		return models.Party{}, ErrPartyNotFound
+	}
+	p.ID, p.CreatedAt, p.UpdatedAt = id, existing.CreatedAt, time.Now()
+	*existing = p
+	return p, nil
+}
+
+// Delete removes a party from the directory
+func (d *Directory) Delete(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.parties[id]; !exists {
+		return ErrPartyNotFound
+	}
+	delete(d.parties, id)
+	return nil
+}
+
+// normalize checks a party's details and stores them in the same form as answers:
+// entity types and jurisdictions spelled out, addresses on one line
+func normalize(p models.Party) (models.Party, error) {
+	p.LegalName = strings.TrimSpace(p.LegalName)
+	if p.LegalName == "" {
+		return models.Party{}, fmt.Errorf("%w: legalName is required", ErrInvalidParty)
+	}
+
+	for _, attr := range []struct {
+		value     *string
+		fieldType string
+	}{
+		{&p.EntityType, utils.TypeEntityType},
+		{&p.Jurisdiction, utils.TypeJurisdiction},
+		{&p.Email, utils.TypeEmail},
+		{&p.SignatoryName, utils.TypeText},
+		{&p.SignatoryTitle, utils.TypeText},
+	} {
+		normalized, err := utils.NormalizeValue(attr.fieldType, *attr.value, nil)
+		if err != nil {
+			return models.Party{}, fmt.Errorf("%w: %v", ErrInvalidParty, err)
+		}
+		*attr.value = normalized
+	}
+
+	if strings.TrimSpace(p.Address) != "" {
+		addr, err := utils.ParseAddress(p.Address)
+		if err != nil {
+			return models.Party{}, fmt.Errorf("%w: %v", ErrInvalidParty, err)
+		}
+		p.Address = addr.Format(utils.AddressSingleLine)
+	} else {
+		p.Address = ""
+	}
+	return p, nil
+}
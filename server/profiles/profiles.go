@@ -0,0 +1,271 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown document type")
+)
+
+// Generic is used when a document matches no specific profile
+const Generic = "generic"
+
+// Profile tunes field detection and typing for one kind of document
+type Profile struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+
+	// Keywords are matched against the document text for auto-classification
+	Keywords []string `json:"keywords"`
+
+	// Examples of placeholders to include, shown in the detection prompt
+	Examples []string `json:"examples"`
+
+	// Exclusions are extra rules for bracketed text that is NOT a placeholder
+	Exclusions []string `json:"exclusions"`
+
+	// BlankExamples show how to name underscore blanks from context
+	BlankExamples []string `json:"blankExamples"`
+
+	// Vocabulary lists the field names commonly found in this document type
+	Vocabulary []string `json:"vocabulary"`
+
+	// FieldTypes maps a field name keyword to its default type
+	FieldTypes map[string]string `json:"fieldTypes"`
+
+	// excludePatterns are matched against bracket contents by the pattern detector
+	excludePatterns []*regexp.Regexp
+
+	// keywordPatterns match Keywords as whole words; compiled once in init
+	keywordPatterns []*regexp.Regexp
+}
+
+// FieldType returns the profile's default type for a field, falling back to name inference.
+// Yes/no names (is_, has_, ...) are always boolean.
+func (p *Profile) FieldType(field string) string {
+	return p.ExplainFieldType(field, "", "").Type
+}
+
+// ExplainFieldType infers a field's type and why. Document context and the custom
+// dictionary come first, then the profile's keywords, then the built-in ones.
+// before and after are the document text around the placeholder, if known.
+func (p *Profile) ExplainFieldType(field, before, after string) utils.TypeInference {
+	inferred := utils.InferFieldTypeInContext(field, before, after)
+	if inferred.Type == utils.TypeBoolean || inferred.Source == utils.SourceContext || inferred.Source == utils.SourceDictionary {
+		return inferred
+	}
+	if fieldType, ok := p.FieldTypes[field]; ok {
+		return utils.TypeInference{Type: fieldType, Source: utils.SourceProfile, Reason: fmt.Sprintf("%s documents use %s for %s", p.Name, fieldType, field)}
+	}
+	for _, word := range strings.Split(field, "_") {
+		if fieldType, ok := p.FieldTypes[word]; ok {
+			return utils.TypeInference{Type: fieldType, Source: utils.SourceProfile, Reason: fmt.Sprintf("%s documents use %s for fields named %q", p.Name, fieldType, word)}
+		}
+	}
+	return inferred
+}
+
+// Excludes reports whether bracketed text is static for this document type
+func (p *Profile) Excludes(inner string) bool {
+	for _, re := range p.excludePatterns {
+		if re.MatchString(inner) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the named profile
+func Get(name string) (*Profile, error) {
+	p, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, ErrUnknownProfile
+	}
+	return p, nil
+}
+
+// List returns all profiles sorted by name
+func List() []*Profile {
+	list := make([]*Profile, 0, len(registry))
+	for _, p := range registry {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Classify picks the profile whose keywords appear most often in the document text.
+// Documents with fewer than two keyword hits are treated as generic.
+func Classify(text string) *Profile {
+	lower := strings.ToLower(text)
+
+	best, bestScore := registry[Generic], 1
+	for _, p := range List() {
+		score := 0
+		for _, re := range p.keywordPatterns {
+			score += len(re.FindAllStringIndex(lower, -1))
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+func init() {
+	for _, p := range registry {
+		for _, keyword := range p.Keywords {
+			// Whole words only, so "rent" doesn't match "current"
+			p.keywordPatterns = append(p.keywordPatterns, regexp.MustCompile(`\b`+regexp.QuoteMeta(strings.ToLower(keyword))+`\b`))
+		}
+	}
+}
+
+var registry = map[string]*Profile{
+	Generic: {
+		Name:  Generic,
+		Label: "General",
+		Examples: []string{
+			"[Company Name], [Investor Name], [Date]",
+			"{{client_name}}, {{contract_amount}}",
+		},
+		BlankExamples: []string{
+			`If you see "$[_____________] (the "Fee")", identify it as "Fee"`,
+			`If you see "by [Client Name]", identify it as "Client Name"`,
+		},
+	},
+	"safe": {
+		Name:     "safe",
+		Label:    "SAFE",
+		Keywords: []string{"simple agreement for future equity", "safe", "valuation cap", "discount rate", "purchase amount", "equity financing", "liquidity event"},
+		Examples: []string{
+			"[Company Name], [Investor Name], [Date]",
+			"{{client_name}}, {{contract_amount}}",
+			"$[_____________] or $[__________] when they represent fields to be filled (look at nearby text for context)",
+		},
+		Exclusions: []string{
+			"Section references like [Section 1(d)] and defined terms like \"Equity Financing\" or \"Liquidity Event\"",
+		},
+		BlankExamples: []string{
+			`If you see "$[_____________] (the "Purchase Amount")", identify it as "Purchase Amount"`,
+			`If you see "by [Investor Name]", identify it as "Investor Name"`,
+		},
+		Vocabulary: []string{"Company Name", "Investor Name", "Date of Safe", "Purchase Amount", "Valuation Cap", "Discount Rate", "State of Incorporation", "Governing Law Jurisdiction"},
+		FieldTypes: map[string]string{
+			"purchase_amount": "currency",
+			"valuation_cap":   "currency",
+			"discount":        "percent",
+			"incorporation":   "jurisdiction",
+		},
+	},
+	"nda": {
+		Name:     "nda",
+		Label:    "Non-Disclosure Agreement",
+		Keywords: []string{"non-disclosure", "nondisclosure", "confidential information", "disclosing party", "receiving party", "confidentiality"},
+		Examples: []string{
+			"[Disclosing Party], [Receiving Party], [Effective Date]",
+			"{{governing_law}}, {{term_years}}",
+		},
+		Exclusions: []string{
+			"Defined role labels used after their definition, like \"Disclosing Party\" or \"Receiving Party\" without brackets",
+			"Defined terms in quotes such as \"Confidential Information\"",
+		},
+		BlankExamples: []string{
+			`If you see "for a period of [___] years", identify it as "Term Years"`,
+			`If you see "laws of the State of [_______]", identify it as "Governing Law"`,
+		},
+		Vocabulary: []string{"Disclosing Party", "Receiving Party", "Effective Date", "Term Years", "Governing Law", "Purpose"},
+		FieldTypes: map[string]string{
+			"term":    "number",
+			"purpose": "multiline",
+		},
+		excludePatterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^confidential$`),
+		},
+	},
+	"employment": {
+		Name:     "employment",
+		Label:    "Employment Offer",
+		Keywords: []string{"offer letter", "employment", "employee", "at-will", "base salary", "start date", "job title", "benefits"},
+		Examples: []string{
+			"[Candidate Name], [Job Title], [Start Date]",
+			"{{base_salary}}, {{manager_name}}",
+		},
+		Exclusions: []string{
+			"Policy and plan names like [Employee Handbook] or [401(k) Plan]",
+		},
+		BlankExamples: []string{
+			`If you see "an annual base salary of $[________]", identify it as "Base Salary"`,
+			`If you see "reporting to [________]", identify it as "Manager Name"`,
+		},
+		Vocabulary: []string{"Employee Name", "Job Title", "Start Date", "Base Salary", "Manager Name", "Work Location", "Equity Grant", "Offer Expiration Date"},
+		FieldTypes: map[string]string{
+			"salary": "currency",
+			"bonus":  "currency",
+			"shares": "number",
+			"title":  "text",
+		},
+		excludePatterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)handbook|plan$`),
+		},
+	},
+	"lease": {
+		Name:     "lease",
+		Label:    "Lease",
+		Keywords: []string{"landlord", "tenant", "lessor", "lessee", "premises", "rent", "security deposit", "lease term"},
+		Examples: []string{
+			"[Landlord Name], [Tenant Name], [Premises Address]",
+			"{{monthly_rent}}, {{lease_start_date}}",
+		},
+		Exclusions: []string{
+			"Exhibit and rider references like [Exhibit A] or [Rider 1]",
+		},
+		BlankExamples: []string{
+			`If you see "monthly rent of $[________]", identify it as "Monthly Rent"`,
+			`If you see "a security deposit of $[______]", identify it as "Security Deposit"`,
+		},
+		Vocabulary: []string{"Landlord Name", "Tenant Name", "Premises Address", "Monthly Rent", "Security Deposit", "Lease Start Date", "Lease Term Months", "Rent Due Day"},
+		FieldTypes: map[string]string{
+			"rent":    "currency",
+			"deposit": "currency",
+			"term":    "number",
+			"address": "address",
+		},
+		excludePatterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^rider\s`),
+		},
+	},
+	"invoice": {
+		Name:     "invoice",
+		Label:    "Invoice",
+		Keywords: []string{"invoice", "bill to", "due date", "subtotal", "qty", "unit price", "payment terms", "amount due"},
+		Examples: []string{
+			"[Invoice Number], [Invoice Date], [Bill To]",
+			"{{subtotal}}, {{tax}}, {{total_due}}",
+		},
+		Exclusions: []string{
+			"Table column headers like [Qty], [Description], [Unit Price] or [Amount]",
+		},
+		BlankExamples: []string{
+			`If you see "Invoice #[_____]", identify it as "Invoice Number"`,
+			`If you see "Total Due: $[______]", identify it as "Total Due"`,
+		},
+		Vocabulary: []string{"Invoice Number", "Invoice Date", "Due Date", "Bill To", "Subtotal", "Tax", "Total Due", "Payment Terms"},
+		FieldTypes: map[string]string{
+			"subtotal":       "currency",
+			"tax":            "currency",
+			"total":          "currency",
+			"invoice_number": "text",
+		},
+		excludePatterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^(qty|quantity|description|unit price|price|amount|item)$`),
+		},
+	},
+}
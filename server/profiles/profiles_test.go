@@ -0,0 +1,40 @@
+package profiles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClassify tests picking a document type from keyword hits in the text
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"MUTUAL NON-DISCLOSURE AGREEMENT. The Disclosing Party may share Confidential Information with the Receiving Party.", "nda"},
+		{"RESIDENTIAL LEASE. Landlord rents the Premises to Tenant. Rent is due monthly and the Security Deposit is held by Landlord.", "lease"},
+		{"Dear [Candidate Name], we are pleased to make this offer letter for employment. Your base salary will be [Salary] and your start date is [Start Date].", "employment"},
+		{"SIMPLE AGREEMENT FOR FUTURE EQUITY. The Purchase Amount converts at the Valuation Cap on an Equity Financing.", "safe"},
+		{"INVOICE #[Number]. Bill To: [Client]. Subtotal, tax and Amount Due by the Due Date.", "invoice"},
+		// Keywords only count as whole words: "current" and "parental" don't mean rent
+		{"The current parental policy applies to [Name].", Generic},
+		// One hit isn't enough to pick a type
+		{"This agreement is between [Party A] and [Party B]. Each tenant of the building is notified.", Generic},
+		{"", Generic},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Classify(tt.text).Name, tt.text)
+	}
+}
+
+// TestGet tests looking up profiles by name
+func TestGet(t *testing.T) {
+	p, err := Get(" NDA ")
+	require.NoError(t, err)
+	assert.Equal(t, "nda", p.Name)
+
+	_, err = Get("mortgage")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+}
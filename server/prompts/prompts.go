@@ -0,0 +1,213 @@
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt names, one template file each (<name>.tmpl)
+const (
+	Detection  = "detection"  // Placeholder detection at upload
+	Mapping    = "mapping"    // Field -> exact placeholder text when filling
+	Questions  = "questions"  // Conversational question phrasing
+	Extraction = "extraction" // Answers pulled from a free-text chat message
+	Prefill    = "prefill"    // Answers proposed from a term sheet or prior agreement
+)
+
+// Templates use [[ ]] delimiters because prompts themselves talk about {{field}} placeholders
+const (
+	leftDelim  = "[["
+	rightDelim = "]]"
+)
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var (
+	ErrPromptNotFound = errors.New("prompt template not found")
+
+	versionComment = regexp.MustCompile(`version:\s*([^\s*]+)`)
+)
+
+// Template is a parsed prompt with its version
+type Template struct {
+	Name    string `json:"name"`
+	Version string `json:"version"` // Declared version plus content hash, e.g. "1+3fa2c9d1"
+	Source  string `json:"source"`  // "embedded" or the override file path
+	tmpl    *template.Template
+}
+
+// Rendered is a prompt ready to send
+type Rendered struct {
+	System  string
+	Prompt  string
+	Version string
+}
+
+var (
+	mu     sync.RWMutex
+	loaded map[string]*Template
+)
+
+// Reload reads embedded defaults, then overrides from PROMPTS_DIR. On error the
+// previously loaded templates stay in place.
+func Reload() error {
+	templates, err := Load(os.Getenv("PROMPTS_DIR"))
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	loaded = templates
+	mu.Unlock()
+	return nil
+}
+
+// Load parses the embedded templates and any <name>.tmpl files in dir, which take precedence
+func Load(dir string) (map[string]*Template, error) {
+	templates := make(map[string]*Template)
+
+	entries, err := embedded.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := embedded.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		t, err := parse(strings.TrimSuffix(entry.Name(), ".tmpl"), "embedded", data)
+		if err != nil {
+			return nil, err
+		}
+		templates[t.Name] = t
+	}
+
+	if dir == "" {
+		return templates, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parse(strings.TrimSuffix(filepath.Base(path), ".tmpl"), path, data)
+		if err != nil {
+			return nil, err
+		}
+		templates[t.Name] = t
+	}
+
+	return templates, nil
+}
+
+// Render executes the named prompt with data
+func Render(name string, data any) (Rendered, error) {
+	t, err := get(name)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	system, err := execute(t.tmpl, "system", data)
+	if err != nil {
+		return Rendered{}, fmt.Errorf("prompt %s: %w", name, err)
+	}
+	prompt, err := execute(t.tmpl, "prompt", data)
+	if err != nil {
+		return Rendered{}, fmt.Errorf("prompt %s: %w", name, err)
+	}
+
+	return Rendered{System: system, Prompt: prompt, Version: t.Version}, nil
+}
+
+// Version returns the current version of the named prompt, or "" if unknown
+func Version(name string) string {
+	t, err := get(name)
+	if err != nil {
+		return ""
+	}
+	return t.Version
+}
+
+// List returns all loaded prompts sorted by name
+func List() []Template {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Template, 0, len(loaded))
+	for _, t := range loaded {
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func get(name string) (*Template, error) {
+	mu.RLock()
+	empty := loaded == nil
+	mu.RUnlock()
+
+	// Lazily load so packages and tests work without explicit initialization
+	if empty {
+		if err := Reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	t, ok := loaded[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	return t, nil
+}
+
+func parse(name, source string, data []byte) (*Template, error) {
+	tmpl, err := template.New(name).
+		Delims(leftDelim, rightDelim).
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Option("missingkey=error").
+		Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt %s (%s): %w", name, source, err)
+	}
+	for _, section := range []string{"system", "prompt"} {
+		if tmpl.Lookup(section) == nil {
+			return nil, fmt.Errorf("prompt %s (%s) is missing a %q block", name, source, section)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:4])
+	if m := versionComment.FindSubmatch(data); m != nil {
+		version = string(m[1]) + "+" + version
+	}
+
+	return &Template{Name: name, Version: version, Source: source, tmpl: tmpl}, nil
+}
+
+func execute(tmpl *template.Template, section string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, section, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,44 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderEmbeddedQuestions tests that the embedded questions prompt renders the field list
+func TestRenderEmbeddedQuestions(t *testing.T) {
+	rendered, err := Render(Questions, struct{ Fields, Types []string }{[]string{"company_name", "investor_name"}, []string{"text", "currency"}})
+	require.NoError(t, err)
+
+	assert.Contains(t, rendered.Prompt, "- company_name\n- investor_name")
+	assert.Contains(t, rendered.Prompt, `"client_name": {"question"`)
+	assert.Contains(t, rendered.Prompt, "input type: text, currency")
+	assert.True(t, strings.HasPrefix(rendered.Version, "2+"))
+	assert.NotEmpty(t, rendered.System)
+}
+
+// TestLoadOverrideDirectory tests that files in the override directory replace embedded defaults
+func TestLoadOverrideDirectory(t *testing.T) {
+	dir := t.TempDir()
+	override := `[[/* version: 2 */]]
+[[define "system"]]Be brief.[[end]]
+[[define "prompt"]]Fields: [[join .Fields ", "]][[end]]`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "questions.tmpl"), []byte(override), 0o644))
+
+	templates, err := Load(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, dir+"/questions.tmpl", templates[Questions].Source)
+	assert.True(t, strings.HasPrefix(templates[Questions].Version, "2+"))
+	assert.Equal(t, "embedded", templates[Detection].Source)
+
+	// A template without the required blocks is rejected
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mapping.tmpl"), []byte(`[[define "system"]]x[[end]]`), 0o644))
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
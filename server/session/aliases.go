@@ -0,0 +1,169 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// aliasStopWords are ignored when comparing field names, so company, company_name
+// and name_of_company all reduce to "company"
+var aliasStopWords = map[string]bool{"name": true, "of": true, "the": true, "a": true, "an": true, "full": true}
+
+// Canonical returns the field an alias was merged into, or field itself
+func Canonical(s *models.Session, field string) string {
+	if canonical, ok := s.Aliases[field]; ok {
+		return canonical
+	}
+	return field
+}
+
+// MergeFields folds aliases into canonical so the value is asked once and written to
+// every spelling of the placeholder. If canonical has no answer it takes the first
+// alias answer; alias answers are then cleared.
+func MergeFields(s *models.Session, canonical string, aliases []string, by Editor) error {
+	if !HasField(s, canonical) {
+		return fmt.Errorf("%w: field %q does not exist", ErrInvalidMerge, canonical)
+	}
+	for _, alias := range aliases {
+		if alias == canonical {
+			return fmt.Errorf("%w: %s cannot be merged into itself", ErrInvalidMerge, alias)
+		}
+		if !HasField(s, alias) {
+			return fmt.Errorf("%w: field %q does not exist", ErrInvalidMerge, alias)
+		}
+		if IsComputed(s, alias) || IsComputed(s, canonical) {
+			return fmt.Errorf("%w: computed fields can't be merged", ErrInvalidMerge)
+		}
+	}
+
+	for _, alias := range aliases {
+		if answer, ok := s.Answers[alias]; ok {
+			if _, answered := s.Answers[canonical]; !answered {
+				SetAnswer(s, canonical, answer, by)
+			}
+			ClearAnswer(s, alias, by)
+		}
+
+		s.Aliases[alias] = canonical
+		for other, target := range s.Aliases {
+			if target == alias {
+				s.Aliases[other] = canonical
+			}
+		}
+		for field, cond := range s.Conditions {
+			if cond.Field == alias {
+				cond.Field = canonical
+				s.Conditions[field] = cond
+			}
+		}
+
+		delete(s.Conditions, alias)
+		delete(s.Skipped, alias)
+		delete(s.Optional, alias)
+		delete(s.Proposals, alias)
+		s.Fields = removeField(s.Fields, alias)
+	}
+	return nil
+}
+
+// SplitField undoes a merge: the alias becomes a separate, unanswered field again,
+// asked right after the field it was merged into. An alias left by RenameField has
+// no settings of its own, so it takes the canonical field's type and section, and
+// its hand-set placeholder back.
+func SplitField(s *models.Session, alias string) error {
+	canonical, ok := s.Aliases[alias]
+	if !ok {
+		return fmt.Errorf("%w: %q is not an alias", ErrInvalidMerge, alias)
+	}
+	delete(s.Aliases, alias)
+
+	if _, typed := s.FieldTypes[alias]; !typed {
+		s.FieldTypes[alias] = s.FieldTypes[canonical]
+		s.FieldTypeReasons[alias] = s.FieldTypeReasons[canonical]
+		s.FieldGroups[alias] = s.FieldGroups[canonical]
+		if o, ok := s.FieldOptions[canonical]; ok {
+			s.FieldOptions[alias] = o
+		}
+		if f, ok := s.Formats[canonical]; ok {
+			s.Formats[alias] = f
+		}
+		if p, ok := s.Placeholders[canonical]; ok {
+			s.Placeholders[alias] = p
+			delete(s.Placeholders, canonical)
+		}
+	}
+
+	fields := make([]string, 0, len(s.Fields)+1)
+	for _, field := range s.Fields {
+		fields = append(fields, field)
+		if field == canonical {
+			fields = append(fields, alias)
+		}
+	}
+	s.Fields = fields
+	return nil
+}
+
+// SuggestMerges finds fields whose names differ only by filler words such as
+// "name" or "of", suggesting the first in interview order as the canonical field
+func SuggestMerges(s *models.Session) []models.MergeSuggestion {
+	groups := make(map[string][]string)
+	var keys []string
+	for _, field := range s.Fields {
+		if IsComputed(s, field) {
+			continue
+		}
+		key := aliasKey(field)
+		if key == "" {
+			continue
+		}
+		if _, seen := groups[key]; !seen {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], field)
+	}
+
+	suggestions := []models.MergeSuggestion{}
+	for _, key := range keys {
+		if fields := groups[key]; len(fields) > 1 {
+			suggestions = append(suggestions, models.MergeSuggestion{
+				Canonical: fields[0],
+				Aliases:   fields[1:],
+				Reason:    "Names match apart from filler words: " + strings.ReplaceAll(key, " ", ", "),
+			})
+		}
+	}
+	return suggestions
+}
+
+// aliasKey reduces a field name to its significant words, singular and sorted
+func aliasKey(field string) string {
+	var words []string
+	for _, word := range strings.Split(field, "_") {
+		if word == "" || aliasStopWords[word] {
+			continue
+		}
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = strings.TrimSuffix(word, "s")
+		}
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func removeField(fields []string, field string) []string {
+	result := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f != field {
+			result = append(result, f)
+		}
+	}
+	return result
+}
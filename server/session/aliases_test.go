@@ -0,0 +1,94 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// TestSplitField tests turning merged and renamed aliases back into fields
+func TestSplitField(t *testing.T) {
+	by := Editor{Source: "answer"}
+	tests := []struct {
+		name      string
+		setup     func(s *models.Session) error
+		alias     string
+		wantErr   bool
+		wantType  string
+		wantGroup string
+		wantAfter string // Field the alias is asked right after
+	}{
+		{
+			name: "merged alias keeps its own settings",
+			setup: func(s *models.Session) error {
+				s.FieldTypes["company_name"] = "multiline"
+				return MergeFields(s, "company_name", []string{"name_of_company"}, by)
+			},
+			alias:     "name_of_company",
+			wantType:  "text",
+			wantGroup: "parties",
+			wantAfter: "company_name",
+		},
+		{
+			name: "renamed field takes the canonical field's settings",
+			setup: func(s *models.Session) error {
+				if err := AddField(s, "closing_fee", "[Fee]", "currency", nil, "set by hand"); err != nil {
+					return err
+				}
+				return RenameField(s, "closing_fee", "fee", by)
+			},
+			alias:     "closing_fee",
+			wantType:  "currency",
+			wantGroup: "economics",
+			wantAfter: "fee",
+		},
+		{
+			name:    "not an alias",
+			setup:   func(s *models.Session) error { return nil },
+			alias:   "company_name",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, s := newTestSession(t, "company_name", "name_of_company", "purchase_amount")
+			require.NoError(t, tt.setup(s))
+
+			err := SplitField(s, tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidMerge)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, s.Aliases, tt.alias)
+			assert.Equal(t, tt.wantType, s.FieldTypes[tt.alias])
+			assert.Equal(t, tt.wantGroup, s.FieldGroups[tt.alias])
+			i := indexOf(s.Fields, tt.alias)
+			require.Positive(t, i)
+			assert.Equal(t, tt.wantAfter, s.Fields[i-1])
+		})
+	}
+}
+
+// TestSplitRenamedFieldPlaceholder tests that a renamed hand-added field's placeholder
+// goes back to the split field
+func TestSplitRenamedFieldPlaceholder(t *testing.T) {
+	_, s := newTestSession(t, "company_name")
+	require.NoError(t, AddField(s, "closing_fee", "[Fee]", "currency", nil, "set by hand"))
+	require.NoError(t, RenameField(s, "closing_fee", "fee", Editor{}))
+	assert.Equal(t, map[string]string{"fee": "[Fee]"}, s.Placeholders)
+
+	require.NoError(t, SplitField(s, "closing_fee"))
+	assert.Equal(t, map[string]string{"closing_fee": "[Fee]"}, s.Placeholders)
+}
+
+func indexOf(list []string, item string) int {
+	for i, v := range list {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+var ErrInvalidCollaborator = errors.New("invalid collaborator")
+
+// Owner is the role of whoever holds the owner token, or anyone while no
+// collaborators are invited
+const Owner = "owner"
+
+// SetCollaborator assigns fields to a role, creating its access token the first time.
+// A field belongs to at most one role; assigning it again moves it.
+func SetCollaborator(s *models.Session, role string, fields []string) (models.Collaborator, error) {
+	if role == "" || role == Owner {
+		return models.Collaborator{}, fmt.Errorf("%w: role is required and can't be %q", ErrInvalidCollaborator, Owner)
+	}
+
+	var assigned []string
+	for _, field := range fields {
+		field = Canonical(s, field)
+		if !HasField(s, field) || IsComputed(s, field) {
+			return models.Collaborator{}, fmt.Errorf("%w: %s is not a field that can be answered", ErrInvalidCollaborator, field)
+		}
+		if !slices.Contains(assigned, field) {
+			assigned = append(assigned, field)
+		}
+	}
+	if len(assigned) == 0 {
+		return models.Collaborator{}, fmt.Errorf("%w: at least one field is required", ErrInvalidCollaborator)
+	}
+
+	collaborator, exists := s.Collaborators[role]
+	if !exists {
+		token, err := NewID()
+		if err != nil {
+			return models.Collaborator{}, err
+		}
+		collaborator = models.Collaborator{Role: role, Token: token, CreatedAt: time.Now()}
+	}
+	collaborator.Fields = assigned
+
+	for other, c := range s.Collaborators {
+		if other != role {
+			c.Fields = slices.DeleteFunc(c.Fields, func(f string) bool { return slices.Contains(assigned, Canonical(s, f)) })
+			s.Collaborators[other] = c
+		}
+	}
+	s.Collaborators[role] = collaborator
+	return collaborator, nil
+}
+
+// Authorize resolves an access token to a role. Sessions without collaborators are
+// open to anyone with the ID; after that the owner token or a role's token is needed.
+func Authorize(s *models.Session, token string) (string, bool) {
+	if len(s.Collaborators) == 0 || tokenMatches(s.OwnerToken, token) {
+		return Owner, true
+	}
+	for role, c := range s.Collaborators {
+		if tokenMatches(c.Token, token) {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// tokenMatches compares tokens in constant time
+func tokenMatches(want, got string) bool {
+	return got != "" && subtle.ConstantTimeCompare([]byte(want), []byte(got)) == 1
+}
+
+// AssignedFields returns the fields a role may answer, in interview order, or nil
+// (every field) for the owner. Fields renamed or merged since are followed to
+// their current name; removed ones are dropped.
+func AssignedFields(s *models.Session, role string) []string {
+	if role == Owner {
+		return nil
+	}
+	fields := []string{}
+	for _, field := range s.Fields {
+		if assignedTo(s, field) == role {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// CanAnswer reports whether a role may answer or revisit a field
+func CanAnswer(s *models.Session, role, field string) bool {
+	return role == Owner || assignedTo(s, Canonical(s, field)) == role
+}
+
+// assignedTo returns the role a field is assigned to, or ""
+func assignedTo(s *models.Session, field string) string {
+	for role, c := range s.Collaborators {
+		for _, f := range c.Fields {
+			if Canonical(s, f) == field {
+				return role
+			}
+		}
+	}
+	return ""
+}
+
+// Progress reports each role's answered fields, sorted by role, and the
+// answerable fields left to the owner
+func Progress(s *models.Session) ([]models.CollaboratorProgress, []string) {
+	progress := []models.CollaboratorProgress{}
+	for role := range s.Collaborators {
+		fields := AssignedFields(s, role)
+		p := models.CollaboratorProgress{Role: role, Fields: fields}
+		p.Answered, p.Total = CompletionAmong(s, fields)
+		_, left := NextFieldAmong(s, fields)
+		p.Done = !left
+		progress = append(progress, p)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Role < progress[j].Role })
+
+	unassigned := []string{}
+	for _, field := range s.Fields {
+		if !IsComputed(s, field) && assignedTo(s, field) == "" {
+			unassigned = append(unassigned, field)
+		}
+	}
+	return progress, unassigned
+}
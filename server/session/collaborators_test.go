@@ -0,0 +1,71 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// newSharedSession creates a session with an investor collaborator assigned investor_name
+func newSharedSession(t *testing.T) (*models.Session, models.Collaborator) {
+	t.Helper()
+	_, s := newTestSession(t, "company_name", "investor_name", "purchase_amount")
+	investor, err := SetCollaborator(s, "investor", []string{"investor_name"})
+	require.NoError(t, err)
+	return s, investor
+}
+
+// TestAuthorize tests resolving access tokens to roles
+func TestAuthorize(t *testing.T) {
+	s, investor := newSharedSession(t)
+	tests := []struct {
+		name     string
+		token    string
+		wantRole string
+		wantOK   bool
+	}{
+		{"owner token", s.OwnerToken, Owner, true},
+		{"collaborator token", investor.Token, "investor", true},
+		{"no token", "", "", false},
+		{"unknown token", "not-a-token", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, ok := Authorize(s, tt.token)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantRole, role)
+		})
+	}
+
+	// Until someone is invited, anyone with the ID is the owner
+	_, open := newTestSession(t, "company_name")
+	role, ok := Authorize(open, "")
+	assert.True(t, ok)
+	assert.Equal(t, Owner, role)
+}
+
+// TestCanAnswer tests which fields each role may answer
+func TestCanAnswer(t *testing.T) {
+	s, _ := newSharedSession(t)
+	require.NoError(t, RenameField(s, "investor_name", "investor", Editor{}))
+	tests := []struct {
+		name  string
+		role  string
+		field string
+		want  bool
+	}{
+		{"owner answers any field", Owner, "company_name", true},
+		{"owner answers assigned fields too", Owner, "investor", true},
+		{"collaborator answers their field", "investor", "investor", true},
+		{"assignment follows a rename", "investor", "investor_name", true},
+		{"collaborator can't answer others' fields", "investor", "company_name", false},
+		{"unknown role", "counsel", "investor", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CanAnswer(s, tt.role, tt.field))
+		})
+	}
+}
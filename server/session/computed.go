@@ -0,0 +1,183 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/you/lexsy-mvp/server/expr"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+var ErrInvalidExpression = errors.New("invalid expression")
+
+// maxComputedDepth is how many computed fields may build on each other in a chain
+const maxComputedDepth = 8
+
+// SetComputed makes field calculated from an expression instead of asked. The
+// expression may only read the session's fields and may not depend on itself,
+// directly or through other computed fields, nor make a chain of computed fields
+// longer than maxComputedDepth.
+func SetComputed(s *models.Session, field, expression string) error {
+	e, err := expr.Parse(expression)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidExpression, err)
+	}
+	for _, dep := range e.Fields() {
+		dep = Canonical(s, dep)
+		if !HasField(s, dep) {
+			return fmt.Errorf("%w: field %q does not exist", ErrInvalidExpression, dep)
+		}
+		if dependsOn(s, dep, field, map[string]bool{}) {
+			return fmt.Errorf("%w: %s cannot depend on itself", ErrInvalidExpression, field)
+		}
+	}
+
+	previous, existed := s.Computed[field]
+	s.Computed[field] = expression
+	depths := make(map[string]int)
+	for f := range s.Computed {
+		if computedDepth(s, f, depths) > maxComputedDepth {
+			if existed {
+				s.Computed[field] = previous
+			} else {
+				delete(s.Computed, field)
+			}
+			return fmt.Errorf("%w: computed fields can build on each other at most %d deep", ErrInvalidExpression, maxComputedDepth)
+		}
+	}
+	return nil
+}
+
+// IsComputed reports whether a field is calculated rather than asked
+func IsComputed(s *models.Session, field string) bool {
+	_, ok := s.Computed[field]
+	return ok
+}
+
+// Compute evaluates a computed field against the current answers. Hidden and
+// optional fields read as blank; other unanswered fields are an error.
+func Compute(s *models.Session, field string) (string, error) {
+	return newEvaluation(s).compute(field)
+}
+
+// ComputedFields describes every computed field with its current value or error
+func ComputedFields(s *models.Session) map[string]models.ComputedField {
+	result := make(map[string]models.ComputedField, len(s.Computed))
+	eval := newEvaluation(s)
+	for field, expression := range s.Computed {
+		cf := models.ComputedField{Expression: expression}
+		if value, err := eval.compute(field); err != nil {
+			cf.Error = err.Error()
+		} else {
+			cf.Value = value
+		}
+		result[field] = cf
+	}
+	return result
+}
+
+// evaluation computes fields against one state of the answers, each field once
+// however many others read it
+type evaluation struct {
+	s        *models.Session
+	results  map[string]result
+	visiting map[string]bool
+}
+
+// result is a computed field's value or error
+type result struct {
+	value string
+	err   error
+}
+
+func newEvaluation(s *models.Session) *evaluation {
+	return &evaluation{s: s, results: make(map[string]result), visiting: make(map[string]bool)}
+}
+
+func (ev *evaluation) compute(field string) (string, error) {
+	if r, ok := ev.results[field]; ok {
+		return r.value, r.err
+	}
+	value, err := ev.evaluate(field)
+	ev.results[field] = result{value, err}
+	return value, err
+}
+
+func (ev *evaluation) evaluate(field string) (string, error) {
+	s := ev.s
+	if ev.visiting[field] {
+		return "", fmt.Errorf("%w: %s depends on itself", ErrInvalidExpression, field)
+	}
+	ev.visiting[field] = true
+	defer delete(ev.visiting, field)
+
+	e, err := expr.Parse(s.Computed[field])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidExpression, err)
+	}
+
+	return e.Eval(func(dep string) (string, error) {
+		dep = Canonical(s, dep)
+		switch {
+		case IsComputed(s, dep):
+			return ev.compute(dep)
+		case !Visible(s, dep):
+			return "", nil
+		}
+		if answer, ok := s.Answers[dep]; ok {
+			if s.FieldTypes[dep] == utils.TypeAddress {
+				// Structured addresses read as one line, not their stored JSON
+				return utils.RenderValue(utils.TypeAddress, answer, utils.AddressSingleLine), nil
+			}
+			return answer, nil
+		}
+		if s.Optional[dep] {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s has no answer yet", dep)
+	})
+}
+
+// computedDepth is the length of the longest chain of computed fields field's value
+// goes through: 0 for an asked field, 1 for one that only reads asked fields
+func computedDepth(s *models.Session, field string, depths map[string]int) int {
+	if depth, ok := depths[field]; ok {
+		return depth
+	}
+	if !IsComputed(s, field) {
+		return 0
+	}
+	depths[field] = 0 // Stops a loop; SetComputed refuses those anyway
+
+	depth := 1
+	if e, err := expr.Parse(s.Computed[field]); err == nil {
+		for _, dep := range e.Fields() {
+			depth = max(depth, 1+computedDepth(s, Canonical(s, dep), depths))
+		}
+	}
+	depths[field] = depth
+	return depth
+}
+
+// dependsOn reports whether field's value reads target, directly or through computed fields
+func dependsOn(s *models.Session, field, target string, seen map[string]bool) bool {
+	if field == target {
+		return true
+	}
+	if seen[field] || !IsComputed(s, field) {
+		return false
+	}
+	seen[field] = true
+
+	e, err := expr.Parse(s.Computed[field])
+	if err != nil {
+		return false
+	}
+	for _, dep := range e.Fields() {
+		if dependsOn(s, Canonical(s, dep), target, seen) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestComputedChains tests how deep computed fields may build on each other
+func TestComputedChains(t *testing.T) {
+	tests := []struct {
+		name    string
+		depth   int // Computed fields chained on top of the answer
+		wantErr bool
+	}{
+		{"one level", 1, false},
+		{"at the limit", maxComputedDepth, false},
+		{"too deep", maxComputedDepth + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := []string{"f0"}
+			for i := 1; i <= tt.depth; i++ {
+				fields = append(fields, fmt.Sprintf("f%d", i))
+			}
+			_, s := newTestSession(t, fields...)
+
+			var err error
+			for i := 1; i <= tt.depth && err == nil; i++ {
+				err = SetComputed(s, fields[i], fmt.Sprintf("f%d + f%d", i-1, i-1))
+			}
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidExpression)
+				assert.NotContains(t, s.Computed, fields[tt.depth])
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestComputedChainJoinsIncreaseDepth tests that linking two chains is refused when
+// the joined chain is too deep, even though the new expression reads no computed field
+func TestComputedChainJoinsIncreaseDepth(t *testing.T) {
+	fields := []string{"f0"}
+	for i := 1; i <= maxComputedDepth; i++ {
+		fields = append(fields, fmt.Sprintf("f%d", i))
+	}
+	_, s := newTestSession(t, append(fields, "base")...)
+	for i := 2; i <= maxComputedDepth; i++ {
+		require.NoError(t, SetComputed(s, fields[i], fmt.Sprintf("f%d + 1", i-1)))
+	}
+
+	// f1 becoming computed makes f8 nine deep
+	require.NoError(t, SetComputed(s, "base", "1"))
+	assert.ErrorIs(t, SetComputed(s, "f1", "base + 1"), ErrInvalidExpression)
+	assert.NotContains(t, s.Computed, "f1")
+}
+
+// TestComputedTextCapped tests that doubling text through a chain stops at the text
+// limit instead of building a huge value
+func TestComputedTextCapped(t *testing.T) {
+	fields := []string{"f0"}
+	for i := 1; i <= maxComputedDepth; i++ {
+		fields = append(fields, fmt.Sprintf("f%d", i))
+	}
+	_, s := newTestSession(t, fields...)
+	s.Answers["f0"] = strings.Repeat("x", 100)
+	for i := 1; i <= maxComputedDepth; i++ {
+		require.NoError(t, SetComputed(s, fields[i], fmt.Sprintf("f%d + f%d", i-1, i-1)))
+	}
+
+	computed := ComputedFields(s)
+	assert.Len(t, computed["f6"].Value, 6400)
+	assert.Empty(t, computed["f8"].Value)
+	assert.Contains(t, computed["f8"].Error, "longer than")
+
+	_, err := FillValues(s)
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// Condition operators
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpIn          = "in"
+	OpAnswered    = "answered"
+	OpNotAnswered = "not_answered"
+)
+
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// Visible reports whether a field should be asked. A field is hidden when its condition
+// doesn't hold or when the field it depends on is itself hidden.
+func Visible(s *models.Session, field string) bool {
+	seen := make(map[string]bool)
+	for {
+		cond, ok := s.Conditions[field]
+		if !ok {
+			return true
+		}
+		if seen[field] || !holds(s, cond) {
+			return false
+		}
+		seen[field] = true
+		field = cond.Field
+	}
+}
+
+// Hidden returns the fields whose conditions don't hold, in interview order
+func Hidden(s *models.Session) []string {
+	hidden := []string{}
+	for _, field := range s.Fields {
+		if !Visible(s, field) {
+			hidden = append(hidden, field)
+		}
+	}
+	return hidden
+}
+
+// SetCondition makes field depend on another field's answer, rejecting unknown
+// operators, unknown fields and chains that loop back to field
+func SetCondition(s *models.Session, field string, cond models.Condition) error {
+	switch cond.Op {
+	case OpEquals, OpNotEquals, OpIn, OpAnswered, OpNotAnswered:
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidCondition, cond.Op)
+	}
+	cond.Field = Canonical(s, cond.Field)
+	if !HasField(s, cond.Field) {
+		return fmt.Errorf("%w: field %q does not exist", ErrInvalidCondition, cond.Field)
+	}
+
+	for dep := cond.Field; ; {
+		if dep == field {
+			return fmt.Errorf("%w: %s cannot depend on itself", ErrInvalidCondition, field)
+		}
+		next, ok := s.Conditions[dep]
+		if !ok {
+			break
+		}
+		dep = next.Field
+	}
+
+	s.Conditions[field] = cond
+	return nil
+}
+
+// holds evaluates a condition against the current answers
+func holds(s *models.Session, cond models.Condition) bool {
+	answer, answered := s.Answers[cond.Field]
+	answer = strings.TrimSpace(answer)
+
+	switch cond.Op {
+	case OpAnswered:
+		return answered && answer != ""
+	case OpNotAnswered:
+		return !answered || answer == ""
+	case OpEquals:
+		return answered && strings.EqualFold(answer, strings.TrimSpace(cond.Value))
+	case OpNotEquals:
+		return answered && !strings.EqualFold(answer, strings.TrimSpace(cond.Value))
+	case OpIn:
+		for _, v := range cond.Values {
+			if answered && strings.EqualFold(answer, strings.TrimSpace(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"slices"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// MergeAcrossDocuments merges likely duplicates that come from different documents
+// of a package (company_name in one, name_of_company in another) so each value is
+// asked once. Look-alikes within a single document are left for the user to merge.
+func MergeAcrossDocuments(s *models.Session, by Editor) []models.MergeSuggestion {
+	merged := []models.MergeSuggestion{}
+	for _, suggestion := range SuggestMerges(s) {
+		group := append([]string{suggestion.Canonical}, suggestion.Aliases...)
+		if !spansDocuments(s, group) {
+			continue
+		}
+		if err := MergeFields(s, suggestion.Canonical, suggestion.Aliases, by); err == nil {
+			merged = append(merged, suggestion)
+		}
+	}
+	return merged
+}
+
+// spansDocuments reports whether the fields don't all come from the same document
+func spansDocuments(s *models.Session, fields []string) bool {
+	for _, doc := range s.Documents {
+		all := true
+		for _, field := range fields {
+			if !slices.Contains(doc.Fields, field) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return false
+		}
+	}
+	return true
+}
+
+// DocumentValues picks the fill values for one package document: its own fields
+// (and their aliases, which FillValues already includes) plus fields added by hand,
+// which belong to no document in particular
+func DocumentValues(s *models.Session, values map[string]string, doc models.Document) map[string]string {
+	picked := make(map[string]string)
+	for _, field := range doc.Fields {
+		if value, ok := values[field]; ok {
+			picked[field] = value
+		}
+	}
+	for _, field := range s.Fields {
+		if !inAnyDocument(s, field) {
+			picked[field] = values[field]
+		}
+	}
+	return picked
+}
+
+// inAnyDocument reports whether a field, or a spelling merged into it, was detected in the package
+func inAnyDocument(s *models.Session, field string) bool {
+	for _, doc := range s.Documents {
+		for _, f := range doc.Fields {
+			if f == field || s.Aliases[f] == field {
+				return true
+			}
+		}
+	}
+	return false
+}
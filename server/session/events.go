@@ -0,0 +1,144 @@
+package session
+
+import (
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// Session event types
+const (
+	EventReady      = "ready"      // Sent once when a watcher connects
+	EventCreated    = "created"    // The session was set up from its documents
+	EventAnswer     = "answer"     // An answer was set or cleared
+	EventCompleted  = "completed"  // The last required field was answered
+	EventQuestions  = "questions"  // AI question generation finished
+	EventGeneration = "generation" // Document generation status changed
+)
+
+// Document generation statuses
+const (
+	GenerationStarted   = "started"
+	GenerationCompleted = "completed"
+	GenerationFailed    = "failed"
+)
+
+// watcherBuffer is how many events a slow watcher may fall behind before events are dropped
+const watcherBuffer = 32
+
+// Notify queues an event for the session's watchers; Store.Update publishes it
+// when the update finishes
+func Notify(s *models.Session, event models.SessionEvent) {
+	s.PendingEvents = append(s.PendingEvents, event)
+}
+
+// Listener is told about every event of every session. It runs while the session is
+// locked, so it may read the session but must not block or update the store.
+type Listener func(s *models.Session, event models.SessionEvent)
+
+// Listen adds a server-wide listener, e.g. for webhooks
+func (s *Store) Listen(fn Listener) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Watch subscribes to a session's events. Call stop when done; the channel is
+// closed when the session is deleted.
+func (s *Store) Watch(id string) (events <-chan models.SessionEvent, stop func(), err error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan models.SessionEvent, watcherBuffer)
+	s.watchMu.Lock()
+	if s.watchers[id] == nil {
+		s.watchers[id] = make(map[chan models.SessionEvent]bool)
+	}
+	s.watchers[id][ch] = true
+	s.watchMu.Unlock()
+
+	stop = func() {
+		s.watchMu.Lock()
+		defer s.watchMu.Unlock()
+		if s.watchers[id][ch] {
+			delete(s.watchers[id], ch)
+			close(ch)
+		}
+		if len(s.watchers[id]) == 0 {
+			delete(s.watchers, id)
+		}
+	}
+	return ch, stop, nil
+}
+
+// collectEvents turns the answer changes made since history index before into
+// events, followed by anything queued with Notify and, when the update answered the
+// last required field, a completed event. The queue is cleared.
+func collectEvents(sess *models.Session, before int, wasComplete bool) []models.SessionEvent {
+	var events []models.SessionEvent
+	for _, change := range sess.History[before:] {
+		events = append(events, models.SessionEvent{
+			Type:   EventAnswer,
+			Field:  change.Field,
+			Answer: change.NewValue,
+			Actor:  change.Actor,
+			Role:   change.Role,
+			Source: change.Source,
+		})
+	}
+	events = append(events, sess.PendingEvents...)
+	sess.PendingEvents = nil
+	if !wasComplete && isComplete(sess) {
+		events = append(events, models.SessionEvent{Type: EventCompleted})
+	}
+
+	now := time.Now()
+	progress, total := Completion(sess)
+	for i := range events {
+		events[i].Progress, events[i].Total = progress, total
+		if events[i].At.IsZero() {
+			events[i].At = now
+		}
+	}
+	return events
+}
+
+// isComplete reports whether every required field is answered
+func isComplete(sess *models.Session) bool {
+	return len(sess.Fields) > 0 && len(MissingRequired(sess)) == 0
+}
+
+// publish hands events to the listeners and sends them to the session's watchers
+// without blocking; a watcher whose buffer is full misses them
+func (s *Store) publish(sess *models.Session, events []models.SessionEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for _, listener := range s.listeners {
+		for _, event := range events {
+			listener(sess, event)
+		}
+	}
+	for ch := range s.watchers[sess.ID] {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// closeWatchers ends every watch on a deleted session
+func (s *Store) closeWatchers(id string) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for ch := range s.watchers[id] {
+		close(ch)
+	}
+	delete(s.watchers, id)
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// TestCollectEvents tests turning an update's changes into session events
+func TestCollectEvents(t *testing.T) {
+	by := Editor{Actor: "owner", Source: "answer"}
+	tests := []struct {
+		name      string
+		answered  map[string]string // Answers before the update
+		update    func(s *models.Session)
+		wantTypes []string
+	}{
+		{
+			name:      "no changes",
+			update:    func(s *models.Session) {},
+			wantTypes: nil,
+		},
+		{
+			name:      "answer",
+			update:    func(s *models.Session) { SetAnswer(s, "company_name", "Acme", by) },
+			wantTypes: []string{EventAnswer},
+		},
+		{
+			name:      "last required answer completes the session",
+			answered:  map[string]string{"company_name": "Acme"},
+			update:    func(s *models.Session) { SetAnswer(s, "investor_name", "Jane", by) },
+			wantTypes: []string{EventAnswer, EventCompleted},
+		},
+		{
+			name:      "already complete",
+			answered:  map[string]string{"company_name": "Acme", "investor_name": "Jane", "notes": "None"},
+			update:    func(s *models.Session) { SetAnswer(s, "investor_name", "John", by) },
+			wantTypes: []string{EventAnswer},
+		},
+		{
+			name: "queued events follow answers",
+			update: func(s *models.Session) {
+				Notify(s, models.SessionEvent{Type: EventQuestions})
+				SetAnswer(s, "company_name", "Acme", by)
+			},
+			wantTypes: []string{EventAnswer, EventQuestions},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, s := newTestSession(t, "company_name", "investor_name", "notes")
+			s.Optional["notes"] = true
+			for field, answer := range tt.answered {
+				SetAnswer(s, field, answer, by)
+			}
+
+			before, wasComplete := len(s.History), isComplete(s)
+			tt.update(s)
+			events := collectEvents(s, before, wasComplete)
+
+			var types []string
+			for _, event := range events {
+				types = append(types, event.Type)
+				// Progress counts only required fields
+				assert.Equal(t, 2-len(MissingRequired(s)), event.Progress)
+				assert.Equal(t, 2, event.Total)
+				assert.False(t, event.At.IsZero())
+			}
+			assert.Equal(t, tt.wantTypes, types)
+			assert.Empty(t, s.PendingEvents)
+			if len(events) > 0 && events[0].Type == EventAnswer {
+				assert.Equal(t, "owner", events[0].Actor)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+var (
+	ErrFieldExists   = errors.New("field already exists")
+	ErrFieldNotFound = errors.New("field not found")
+	ErrFieldInUse    = errors.New("field is used by another field")
+	ErrInvalidType   = errors.New("invalid field type")
+	ErrInvalidFormat = errors.New("invalid field format")
+)
+
+// AddField adds a field the detector missed, asked at the end of its section.
+// placeholder is the exact text to replace in the document, or "" for the standard
+// {{field}} / [Field Name] formats. reason says why the field has its type.
+func AddField(s *models.Session, field, placeholder, fieldType string, options []string, reason string) error {
+	if HasField(s, field) || s.Aliases[field] != "" {
+		return fmt.Errorf("%w: %s", ErrFieldExists, field)
+	}
+	if !utils.IsFieldType(fieldType) {
+		return fmt.Errorf("%w: %q", ErrInvalidType, fieldType)
+	}
+
+	if fieldType == utils.TypeSelect && len(options) == 0 {
+		return fmt.Errorf("%w: select fields need options", ErrInvalidType)
+	}
+
+	s.FieldTypes[field] = fieldType
+	s.FieldTypeReasons[field] = reason
+	s.FieldGroups[field] = utils.InferFieldGroup(field)
+	if len(options) > 0 {
+		s.FieldOptions[field] = options
+	}
+	if placeholder != "" {
+		s.Placeholders[field] = placeholder
+	}
+	s.Fields = utils.GroupFields(append(s.Fields, field), s.FieldGroups)
+	return nil
+}
+
+// RemoveField drops a falsely detected field along with its answer and settings.
+// Fields that other fields' conditions or expressions depend on can't be removed.
+func RemoveField(s *models.Session, field string, by Editor) error {
+	if !HasField(s, field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if user := dependent(s, field); user != "" {
+		return fmt.Errorf("%w: %s depends on %s", ErrFieldInUse, user, field)
+	}
+
+	ClearAnswer(s, field, by)
+	s.Fields = removeField(s.Fields, field)
+	forget(s, field)
+	for alias, canonical := range s.Aliases {
+		if canonical == field {
+			delete(s.Aliases, alias)
+		}
+	}
+	return nil
+}
+
+// RenameField changes a field's key, carrying its answer and settings over. The old
+// key becomes an alias so its placeholder in the document is still filled.
+func RenameField(s *models.Session, field, newName string, by Editor) error {
+	if !HasField(s, field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if HasField(s, newName) || (s.Aliases[newName] != "" && s.Aliases[newName] != field) {
+		return fmt.Errorf("%w: %s", ErrFieldExists, newName)
+	}
+
+	for i, f := range s.Fields {
+		if f == field {
+			s.Fields[i] = newName
+		}
+	}
+
+	if answer, ok := s.Answers[field]; ok {
+		ClearAnswer(s, field, by)
+		SetAnswer(s, newName, answer, by)
+	}
+	if q, ok := s.Questions[field]; ok {
+		s.Questions[newName] = q
+	}
+	if p, ok := s.Proposals[field]; ok {
+		p.Field = newName
+		s.Proposals[newName] = p
+	}
+	if c, ok := s.Conditions[field]; ok {
+		s.Conditions[newName] = c
+	}
+	if e, ok := s.Computed[field]; ok {
+		s.Computed[newName] = e
+	}
+	if p, ok := s.Placeholders[field]; ok {
+		s.Placeholders[newName] = p
+	}
+	if o, ok := s.FieldOptions[field]; ok {
+		s.FieldOptions[newName] = o
+	}
+	if f, ok := s.Formats[field]; ok {
+		s.Formats[newName] = f
+	}
+	s.FieldTypes[newName] = s.FieldTypes[field]
+	s.FieldTypeReasons[newName] = s.FieldTypeReasons[field]
+	s.FieldGroups[newName] = s.FieldGroups[field]
+	if s.Skipped[field] {
+		s.Skipped[newName] = true
+	}
+	if s.Optional[field] {
+		s.Optional[newName] = true
+	}
+	forget(s, field)
+
+	for name, cond := range s.Conditions {
+		if cond.Field == field {
+			cond.Field = newName
+			s.Conditions[name] = cond
+		}
+	}
+	for alias, canonical := range s.Aliases {
+		if canonical == field {
+			s.Aliases[alias] = newName
+		}
+	}
+	delete(s.Aliases, newName)
+	s.Aliases[field] = newName
+	return nil
+}
+
+// SetFieldType changes the input type of a field. Options replace the field's choices
+// when given; select fields must end up with some. reason says why the type was chosen.
+// A saved answer is converted to the new type, or cleared if it doesn't fit.
+func SetFieldType(s *models.Session, field, fieldType string, options []string, reason string, by Editor) error {
+	if err := CheckFieldType(s, field, fieldType, options); err != nil {
+		return err
+	}
+
+	oldType := s.FieldTypes[field]
+	s.FieldTypes[field] = fieldType
+	s.FieldTypeReasons[field] = reason
+	if len(options) > 0 {
+		s.FieldOptions[field] = options
+	}
+	if fieldType != utils.TypeAddress {
+		delete(s.Formats, field)
+	}
+
+	if answer, ok := s.Answers[field]; ok {
+		if oldType == utils.TypeAddress {
+			// The stored JSON reads as one line
+			answer = utils.RenderValue(utils.TypeAddress, answer, utils.AddressSingleLine)
+		}
+		if normalized, err := NormalizeAnswer(s, field, answer); err != nil {
+			ClearAnswer(s, field, by)
+		} else {
+			SetAnswer(s, field, normalized, by)
+		}
+	}
+	return nil
+}
+
+// CheckFieldType reports whether SetFieldType would accept a type and options for a field
+func CheckFieldType(s *models.Session, field, fieldType string, options []string) error {
+	if !HasField(s, field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if !utils.IsFieldType(fieldType) {
+		return fmt.Errorf("%w: %q", ErrInvalidType, fieldType)
+	}
+	if fieldType == utils.TypeSelect && len(options) == 0 && len(s.FieldOptions[field]) == 0 {
+		return fmt.Errorf("%w: select fields need options", ErrInvalidType)
+	}
+	return nil
+}
+
+// SetFieldFormat chooses how a field's answer is written into the document.
+// Only addresses have formats (utils.AddressFormats).
+func SetFieldFormat(s *models.Session, field, format string) error {
+	if !HasField(s, field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if err := CheckFieldFormat(s.FieldTypes[field], format); err != nil {
+		return err
+	}
+
+	s.Formats[field] = format
+	return nil
+}
+
+// CheckFieldFormat reports whether a format applies to a field type
+func CheckFieldFormat(fieldType, format string) error {
+	if fieldType != utils.TypeAddress {
+		return fmt.Errorf("%w: only address fields have formats", ErrInvalidFormat)
+	}
+	if !utils.IsAddressFormat(format) {
+		return fmt.Errorf("%w: %q is not one of: %s", ErrInvalidFormat, format, strings.Join(utils.AddressFormats, ", "))
+	}
+	return nil
+}
+
+// SubFields returns the parts collected separately for a structured field, if any
+func SubFields(s *models.Session, field string) []models.SubField {
+	if s.FieldTypes[field] != utils.TypeAddress {
+		return nil
+	}
+	parts := make([]models.SubField, len(utils.AddressParts))
+	for i, p := range utils.AddressParts {
+		parts[i] = models.SubField{Name: p.Name, Label: p.Label, Required: p.Required}
+	}
+	return parts
+}
+
+// NormalizeAnswer validates a value for the field's type and returns it in stored form
+func NormalizeAnswer(s *models.Session, field, value string) (string, error) {
+	return utils.NormalizeValue(s.FieldTypes[field], value, s.FieldOptions[field])
+}
+
+// FieldOptions returns the choices to offer for a field, if its type has any
+func FieldOptions(s *models.Session, field string) []string {
+	if options := s.FieldOptions[field]; len(options) > 0 {
+		return options
+	}
+	return utils.DefaultOptions(s.FieldTypes[field])
+}
+
+// forget deletes a field's per-field settings (its answer is handled separately)
+func forget(s *models.Session, field string) {
+	delete(s.Questions, field)
+	delete(s.FieldTypes, field)
+	delete(s.FieldTypeReasons, field)
+	delete(s.FieldGroups, field)
+	delete(s.Skipped, field)
+	delete(s.Optional, field)
+	delete(s.Conditions, field)
+	delete(s.Computed, field)
+	delete(s.Proposals, field)
+	delete(s.Placeholders, field)
+	delete(s.FieldOptions, field)
+	delete(s.Formats, field)
+}
+
+// dependent returns a field whose condition or expression reads field, or ""
+func dependent(s *models.Session, field string) string {
+	for _, other := range s.Fields {
+		if other == field {
+			continue
+		}
+		if cond, ok := s.Conditions[other]; ok && Canonical(s, cond.Field) == field {
+			return other
+		}
+		if IsComputed(s, other) && dependsOn(s, other, field, map[string]bool{}) {
+			return other
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+// TestSetFieldTypeConvertsAnswer tests that retyping a field keeps its saved answer
+// valid for the new type
+func TestSetFieldTypeConvertsAnswer(t *testing.T) {
+	by := Editor{Source: "edit"}
+	tests := []struct {
+		name      string
+		fromType  string
+		answer    string // As typed by the user
+		toType    string
+		options   []string
+		want      string
+		wantClear bool
+	}{
+		{"address reads as one line of text", utils.TypeAddress, "1 Main St, Springfield, IL 62701", utils.TypeText, nil, "1 Main St, Springfield, IL 62701", false},
+		{"text amount becomes a number", utils.TypeText, "$5,000", utils.TypeCurrency, nil, "5000", false},
+		{"text that isn't a date is cleared", utils.TypeText, "next week", utils.TypeDate, nil, "", true},
+		{"choice missing from new options is cleared", utils.TypeText, "blue", utils.TypeSelect, []string{"red", "green"}, "", true},
+		{"choice kept in new options", utils.TypeText, "Red", utils.TypeSelect, []string{"red", "green"}, "red", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, s := newTestSession(t, "value")
+			s.FieldTypes["value"] = tt.fromType
+			stored, err := NormalizeAnswer(s, "value", tt.answer)
+			require.NoError(t, err)
+			SetAnswer(s, "value", stored, by)
+
+			require.NoError(t, SetFieldType(s, "value", tt.toType, tt.options, "set by hand", by))
+			answer, ok := s.Answers["value"]
+			assert.Equal(t, !tt.wantClear, ok)
+			assert.Equal(t, tt.want, answer)
+
+			// Every change is in the history
+			last := s.History[len(s.History)-1]
+			if tt.wantClear {
+				assert.Nil(t, last.NewValue)
+			} else if stored != tt.want {
+				require.NotNil(t, last.NewValue)
+				assert.Equal(t, tt.want, *last.NewValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+var (
+	ErrEventNotFound = errors.New("history event not found")
+	ErrNothingToUndo = errors.New("nothing to undo")
+)
+
+// Editor identifies who is changing answers and through which feature. Role is
+// what the request's token grants; Actor is only the name the client gave.
+type Editor struct {
+	Actor  string
+	Role   string
+	IP     string
+	Source string
+}
+
+// SetAnswer sets a field's answer and records the change. Unchanged values are not recorded.
+// Answering a skipped field un-skips it.
+func SetAnswer(s *models.Session, field, value string, by Editor) {
+	delete(s.Skipped, field)
+
+	old, had := s.Answers[field]
+	if had && old == value {
+		return
+	}
+	s.Answers[field] = value
+	record(s, field, optional(old, had), &value, by)
+}
+
+// ClearAnswer removes a field's answer and records the change
+func ClearAnswer(s *models.Session, field string, by Editor) {
+	old, had := s.Answers[field]
+	if !had {
+		return
+	}
+	delete(s.Answers, field)
+	record(s, field, &old, nil, by)
+}
+
+// AnswersAt replays history to the answers as they were right after eventID
+func AnswersAt(s *models.Session, eventID int) (map[string]string, error) {
+	if eventID < 0 || eventID > len(s.History) {
+		return nil, ErrEventNotFound
+	}
+
+	answers := make(map[string]string)
+	for _, e := range s.History[:eventID] {
+		if e.NewValue == nil {
+			delete(answers, e.Field)
+		} else {
+			answers[e.Field] = *e.NewValue
+		}
+	}
+	return answers, nil
+}
+
+// RevertTo restores the session's fields to how they were right after eventID,
+// appending the changes as one change set so the audit trail is never rewritten.
+// Answers recorded under a field's old name follow it through renames and merges;
+// answers of removed fields are left out. Returns the number of fields changed.
+func RevertTo(s *models.Session, eventID int, by Editor) (int, error) {
+	answers, err := AnswersAt(s, eventID)
+	if err != nil {
+		return 0, err
+	}
+	target := make(map[string]string, len(answers))
+	for field, value := range answers {
+		if _, exact := answers[Canonical(s, field)]; !exact {
+			target[Canonical(s, field)] = value
+		}
+	}
+	for field, value := range answers {
+		target[field] = value
+	}
+
+	before := len(s.History)
+	for _, field := range s.Fields {
+		if value, want := target[field]; want {
+			SetAnswer(s, field, value, by)
+		} else {
+			ClearAnswer(s, field, by)
+		}
+	}
+	groupChanges(s, before, 0)
+	return len(s.History) - before, nil
+}
+
+// Undo reverts the most recent change set that hasn't been undone yet, so repeated
+// undos step further back. A change set is every answer changed by one request, e.g.
+// all the answers saved from a message or restored by a revert.
+func Undo(s *models.Session, by Editor) (int, error) {
+	undone := make(map[int]bool)
+	for end := len(s.History) - 1; end >= 0; {
+		set := changeSet(s.History[end])
+		start := end
+		for start > 0 && changeSet(s.History[start-1]) == set {
+			start--
+		}
+
+		if undoes := s.History[end].Undoes; undoes != 0 {
+			undone[undoes] = true
+		} else if !undone[set] {
+			if changed := undoChanges(s, s.History[start:end+1], set, by); changed > 0 {
+				return changed, nil
+			}
+		}
+		end = start - 1
+	}
+	return 0, ErrNothingToUndo
+}
+
+// undoChanges restores each field changed in events to its value from before them,
+// following renames and skipping fields no longer in the session, and records the
+// result as undoing set
+func undoChanges(s *models.Session, events []models.AnswerEvent, set int, by Editor) int {
+	previous := make(map[string]*string)
+	var fields []string
+	for _, e := range events {
+		field := Canonical(s, e.Field)
+		if _, seen := previous[field]; !seen && HasField(s, field) {
+			previous[field] = e.OldValue
+			fields = append(fields, field)
+		}
+	}
+
+	before := len(s.History)
+	for _, field := range fields {
+		if old := previous[field]; old != nil {
+			SetAnswer(s, field, *old, by)
+		} else {
+			ClearAnswer(s, field, by)
+		}
+	}
+	groupChanges(s, before, set)
+	return len(s.History) - before
+}
+
+// groupChanges puts the events recorded since history index before into one change
+// set, marked as undoing another set unless undoes is 0. Events already grouped, e.g.
+// by RevertTo within a larger update, keep their set.
+func groupChanges(s *models.Session, before, undoes int) {
+	first := 0
+	for i := range s.History[before:] {
+		e := &s.History[before+i]
+		if e.ChangeSet != 0 {
+			continue
+		}
+		if first == 0 {
+			first = e.ID
+		}
+		e.ChangeSet, e.Undoes = first, undoes
+	}
+}
+
+// changeSet returns an event's change set; events recorded outside Store.Update
+// without grouping are each their own set
+func changeSet(e models.AnswerEvent) int {
+	if e.ChangeSet == 0 {
+		return e.ID
+	}
+	return e.ChangeSet
+}
+
+func record(s *models.Session, field string, old, new *string, by Editor) {
+	s.History = append(s.History, models.AnswerEvent{
+		ID:        len(s.History) + 1,
+		Field:     field,
+		OldValue:  old,
+		NewValue:  new,
+		Timestamp: time.Now(),
+		Actor:     by.Actor,
+		Role:      by.Role,
+		IP:        by.IP,
+		Source:    by.Source,
+	})
+}
+
+func optional(value string, ok bool) *string {
+	if !ok {
+		return nil
+	}
+	return &value
+}
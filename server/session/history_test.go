@@ -0,0 +1,114 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// newTestSession creates a stored session with the given fields
+func newTestSession(t *testing.T, fields ...string) (*Store, *models.Session) {
+	t.Helper()
+	store := NewStore()
+	sess, err := store.Create([]byte("mock docx bytes"), fields)
+	require.NoError(t, err)
+	return store, sess
+}
+
+// TestUndoStepsBack tests that each undo reverts one more request's changes
+func TestUndoStepsBack(t *testing.T) {
+	by := Editor{Source: "answer"}
+	tests := []struct {
+		name  string
+		edits []map[string]string // One request per entry
+		undos int
+		want  map[string]string
+	}{
+		{"one undo", []map[string]string{{"a": "1"}, {"a": "2"}}, 1, map[string]string{"a": "1"}},
+		{"repeated undo steps back instead of toggling", []map[string]string{{"a": "1"}, {"a": "2"}, {"a": "3"}}, 2, map[string]string{"a": "1"}},
+		{"undo everything", []map[string]string{{"a": "1"}, {"b": "2"}}, 2, map[string]string{}},
+		{"multi-field request undone together", []map[string]string{{"a": "1"}, {"a": "2", "b": "3"}}, 1, map[string]string{"a": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, sess := newTestSession(t, "a", "b")
+			for _, edit := range tt.edits {
+				require.NoError(t, store.Update(sess.ID, func(s *models.Session) {
+					for field, value := range edit {
+						SetAnswer(s, field, value, by)
+					}
+				}))
+			}
+			for i := 0; i < tt.undos; i++ {
+				var err error
+				require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = Undo(s, by) }))
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, sess.Answers)
+		})
+	}
+}
+
+// TestUndoRevert tests that undoing a revert restores every field it changed
+func TestUndoRevert(t *testing.T) {
+	by := Editor{Source: "answer"}
+	store, sess := newTestSession(t, "a", "b", "c")
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) {
+		SetAnswer(s, "a", "1", by)
+		SetAnswer(s, "b", "2", by)
+		SetAnswer(s, "c", "3", by)
+	}))
+
+	var changed int
+	var err error
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { changed, err = RevertTo(s, 1, by) }))
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, map[string]string{"a": "1"}, sess.Answers)
+
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { changed, err = Undo(s, by) }))
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "3"}, sess.Answers)
+
+	// The revert is undone, so the next undo takes back the original answers
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = Undo(s, by) }))
+	require.NoError(t, err)
+	assert.Empty(t, sess.Answers)
+
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = Undo(s, by) }))
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+}
+
+// TestRevertSkipsRemovedFields tests that reverting doesn't restore answers of
+// removed fields and follows renamed ones
+func TestRevertSkipsRemovedFields(t *testing.T) {
+	by := Editor{Source: "answer"}
+	store, sess := newTestSession(t, "company", "investor", "notes")
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) {
+		SetAnswer(s, "company", "Acme", by)
+		SetAnswer(s, "investor", "Jane", by)
+		SetAnswer(s, "notes", "n/a", by)
+	}))
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) {
+		require.NoError(t, RemoveField(s, "notes", by))
+		require.NoError(t, RenameField(s, "company", "company_name", by))
+		SetAnswer(s, "company_name", "Acme Inc.", by)
+		SetAnswer(s, "investor", "John", by)
+	}))
+
+	var err error
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = RevertTo(s, 3, by) }))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"company_name": "Acme", "investor": "Jane"}, sess.Answers)
+
+	// Undoing the revert only touches fields still in the session
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = Undo(s, by) }))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"company_name": "Acme Inc.", "investor": "John"}, sess.Answers)
+	require.NoError(t, store.Update(sess.ID, func(s *models.Session) { _, err = Undo(s, by) }))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"company_name": "Acme", "investor": "Jane"}, sess.Answers)
+}
@@ -0,0 +1,129 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+// NextField picks the next field to ask about: unanswered required fields first,
+// then unanswered optional ones, then fields the user skipped to revisit later.
+// Computed fields and fields hidden by a condition are never asked. Returns false when nothing is left
+// to ask (skipped optional fields are not revisited).
+func NextField(s *models.Session) (string, bool) {
+	return NextFieldAmong(s, nil)
+}
+
+// NextFieldAmong is NextField limited to some fields, e.g. a collaborator's; nil means all
+func NextFieldAmong(s *models.Session, among []string) (string, bool) {
+	var optional, skipped string
+	for _, field := range s.Fields {
+		if among != nil && !slices.Contains(among, field) {
+			continue
+		}
+		if _, answered := s.Answers[field]; answered || IsComputed(s, field) || !Visible(s, field) {
+			continue
+		}
+		switch {
+		case s.Skipped[field]:
+			if skipped == "" && !s.Optional[field] {
+				skipped = field
+			}
+		case s.Optional[field]:
+			if optional == "" {
+				optional = field
+			}
+		default:
+			return field, true
+		}
+	}
+
+	if optional != "" {
+		return optional, true
+	}
+	if skipped != "" {
+		return skipped, true
+	}
+	return "", false
+}
+
+// RequiredFields returns the fields that are not optional, computed or hidden, in interview order
+func RequiredFields(s *models.Session) []string {
+	required := []string{}
+	for _, field := range s.Fields {
+		if !s.Optional[field] && !IsComputed(s, field) && Visible(s, field) {
+			required = append(required, field)
+		}
+	}
+	return required
+}
+
+// MissingRequired returns unanswered required fields, in interview order
+func MissingRequired(s *models.Session) []string {
+	missing := []string{}
+	for _, field := range RequiredFields(s) {
+		if _, answered := s.Answers[field]; !answered {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// Completion counts answered required fields against all required fields. Every
+// progress and total the API reports comes from here, so they agree with completion.
+func Completion(s *models.Session) (answered, total int) {
+	return CompletionAmong(s, nil)
+}
+
+// CompletionAmong is Completion limited to some fields, e.g. a collaborator's; nil means all
+func CompletionAmong(s *models.Session, among []string) (answered, total int) {
+	for _, field := range RequiredFields(s) {
+		if among != nil && !slices.Contains(among, field) {
+			continue
+		}
+		total++
+		if _, ok := s.Answers[field]; ok {
+			answered++
+		}
+	}
+	return answered, total
+}
+
+// HasField reports whether field is one of the session's fields
+func HasField(s *models.Session, field string) bool {
+	for _, f := range s.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FillValues returns the values to write into the document: answers for visible
+// fields, calculated values for computed fields, and blanks for hidden fields and
+// unanswered optional ones, each rendered for its type. Aliases get their canonical
+// field's value.
+func FillValues(s *models.Session) (map[string]string, error) {
+	values := make(map[string]string, len(s.Fields))
+	eval := newEvaluation(s)
+	for _, field := range s.Fields {
+		switch {
+		case !Visible(s, field):
+			values[field] = ""
+		case IsComputed(s, field):
+			value, err := eval.compute(field)
+			if err != nil {
+				return nil, fmt.Errorf("computed field %s: %w", field, err)
+			}
+			values[field] = utils.RenderValue(s.FieldTypes[field], value, s.Formats[field])
+		default:
+			values[field] = utils.RenderValue(s.FieldTypes[field], s.Answers[field], s.Formats[field])
+		}
+	}
+	for alias, canonical := range s.Aliases {
+		values[alias] = values[canonical]
+	}
+	return values, nil
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+// TestCompletion tests that progress counts only required fields
+func TestCompletion(t *testing.T) {
+	by := Editor{Source: "answer"}
+	_, s := newTestSession(t, "company_name", "has_discount", "discount_rate", "notes", "total")
+	s.Optional["notes"] = true
+	require.NoError(t, SetComputed(s, "total", "1 + 1"))
+	require.NoError(t, SetCondition(s, "discount_rate", models.Condition{Field: "has_discount", Op: OpEquals, Value: "Yes"}))
+	SetAnswer(s, "notes", "None", by)
+	SetAnswer(s, "has_discount", "No", by)
+
+	tests := []struct {
+		name         string
+		among        []string
+		wantAnswered int
+		wantTotal    int
+	}{
+		{"all", nil, 1, 2}, // Optional, computed and hidden fields aren't counted
+		{"some", []string{"company_name", "notes"}, 0, 1},
+		{"none required", []string{"notes", "total"}, 0, 0},
+	}
+	for _, tt := range tests {
+		answered, total := CompletionAmong(s, tt.among)
+		assert.Equal(t, tt.wantAnswered, answered, tt.name)
+		assert.Equal(t, tt.wantTotal, total, tt.name)
+	}
+
+	SetAnswer(s, "company_name", "Acme", by)
+	answered, total := Completion(s)
+	assert.Equal(t, 2, answered)
+	assert.Equal(t, 2, total)
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/utils"
+)
+
+var ErrInvalidBinding = errors.New("invalid party binding")
+
+// Party attributes that can fill a role's fields
+const (
+	AttrLegalName      = "legal_name"
+	AttrEntityType     = "entity_type"
+	AttrJurisdiction   = "jurisdiction"
+	AttrAddress        = "address"
+	AttrSignatoryName  = "signatory_name"
+	AttrSignatoryTitle = "signatory_title"
+	AttrEmail          = "email"
+)
+
+// PartyAttributes lists the attributes in the order they're reported
+var PartyAttributes = []string{AttrLegalName, AttrEntityType, AttrJurisdiction, AttrAddress, AttrSignatoryName, AttrSignatoryTitle, AttrEmail}
+
+// partyValue returns one attribute of a party
+func partyValue(p models.Party, attr string) string {
+	switch attr {
+	case AttrLegalName:
+		return p.LegalName
+	case AttrEntityType:
+		return p.EntityType
+	case AttrJurisdiction:
+		return p.Jurisdiction
+	case AttrAddress:
+		return p.Address
+	case AttrSignatoryName:
+		return p.SignatoryName
+	case AttrSignatoryTitle:
+		return p.SignatoryTitle
+	case AttrEmail:
+		return p.Email
+	}
+	return ""
+}
+
+// RoleFields matches a role's fields to party attributes. A field belongs to a role
+// when its name contains the role's words (company_name, company_state, name_of_investor);
+// the field's type and remaining words pick the attribute.
+func RoleFields(s *models.Session, role string) map[string]string {
+	roleWords := strings.Split(role, "_")
+	matched := make(map[string]string)
+	for _, field := range s.Fields {
+		if IsComputed(s, field) {
+			continue
+		}
+		rest, ok := withoutWords(strings.Split(field, "_"), roleWords)
+		if !ok {
+			continue
+		}
+		if attr := roleAttribute(s.FieldTypes[field], rest); attr != "" {
+			matched[field] = attr
+		}
+	}
+	return matched
+}
+
+// roleAttribute picks the party attribute for a role field from its type, then from
+// the words left once the role is removed ("signatory_title" -> signatory title)
+func roleAttribute(fieldType string, rest []string) string {
+	switch fieldType {
+	case utils.TypeEmail:
+		return AttrEmail
+	case utils.TypeAddress:
+		return AttrAddress
+	case utils.TypeEntityType:
+		return AttrEntityType
+	case utils.TypeJurisdiction:
+		return AttrJurisdiction
+	case utils.TypeText:
+	default:
+		return ""
+	}
+
+	rest = slices.DeleteFunc(rest, func(w string) bool { return w == "of" || w == "the" })
+	switch {
+	case slices.Contains(rest, "title"):
+		return AttrSignatoryTitle
+	case slices.ContainsFunc(rest, func(w string) bool {
+		return w == "signatory" || w == "signer" || w == "representative" || w == "officer" || w == "authorized"
+	}):
+		return AttrSignatoryName
+	case len(rest) == 0, slices.Equal(rest, []string{"name"}), slices.Equal(rest, []string{"legal", "name"}),
+		slices.Equal(rest, []string{"full", "name"}), slices.Equal(rest, []string{"entity", "name"}):
+		return AttrLegalName
+	}
+	return ""
+}
+
+// withoutWords removes the first consecutive run of want from words
+func withoutWords(words, want []string) ([]string, bool) {
+	for i := 0; i+len(want) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(want)], want) {
+			return append(append([]string{}, words[:i]...), words[i+len(want):]...), true
+		}
+	}
+	return nil, false
+}
+
+// BindParty fills a role's fields from a party in one step and records the binding.
+// overrides name the attribute for fields the role's naming doesn't reveal
+// (field -> attribute). Values that don't fit a field's type are reported, not saved.
+func BindParty(s *models.Session, role string, p models.Party, overrides map[string]string, by Editor) (models.BindRoleResponse, error) {
+	if role == "" {
+		return models.BindRoleResponse{}, fmt.Errorf("%w: role is required", ErrInvalidBinding)
+	}
+
+	fields := RoleFields(s, role)
+	for field, attr := range overrides {
+		field = Canonical(s, field)
+		if !HasField(s, field) || IsComputed(s, field) {
+			return models.BindRoleResponse{}, fmt.Errorf("%w: %s is not a field that can be answered", ErrInvalidBinding, field)
+		}
+		if !slices.Contains(PartyAttributes, attr) {
+			return models.BindRoleResponse{}, fmt.Errorf("%w: unknown attribute %q (expected one of: %s)", ErrInvalidBinding, attr, strings.Join(PartyAttributes, ", "))
+		}
+		fields[field] = attr
+	}
+	if len(fields) == 0 {
+		return models.BindRoleResponse{}, fmt.Errorf("%w: no fields found for role %s", ErrInvalidBinding, role)
+	}
+
+	resp := models.BindRoleResponse{
+		Role:      role,
+		Party:     p,
+		Filled:    make(map[string]string),
+		Rejected:  make(map[string]string),
+		Unmatched: []string{},
+	}
+	used := make(map[string]bool)
+	for _, field := range s.Fields {
+		attr, ok := fields[field]
+		if !ok {
+			continue
+		}
+		value := partyValue(p, attr)
+		if value == "" {
+			continue
+		}
+		used[attr] = true
+		answer, err := NormalizeAnswer(s, field, value)
+		if err != nil {
+			resp.Rejected[field] = err.Error()
+			continue
+		}
+		SetAnswer(s, field, answer, by)
+		resp.Filled[field] = answer
+	}
+	for _, attr := range PartyAttributes {
+		if !used[attr] && partyValue(p, attr) != "" {
+			resp.Unmatched = append(resp.Unmatched, attr)
+		}
+	}
+
+	s.Parties[role] = p.ID
+	return resp, nil
+}
@@ -19,38 +19,74 @@ var (
 type Store struct {
 	mu       sync.RWMutex
 	sessions map[string]*models.Session
+
+	watchMu   sync.Mutex
+	watchers  map[string]map[chan models.SessionEvent]bool // session ID -> subscribed channels
+	listeners []Listener
 }
 
 // NewStore creates a new session store
 func NewStore() *Store {
 	return &Store{
 		sessions: make(map[string]*models.Session),
+		watchers: make(map[string]map[chan models.SessionEvent]bool),
 	}
 }
 
 // Create creates a new session and returns its ID
 func (s *Store) Create(docBytes []byte, fields []string) (*models.Session, error) {
-	id, err := generateID()
+	id, err := NewID()
 	if err != nil {
 		return nil, err
 	}
 
-	// Infer field types
+	return s.CreateWithID(id, docBytes, fields)
+}
+
+// CreateWithID creates a session under an ID obtained from NewID, so work done
+// before the session exists (e.g. AI detection) can be attributed to it
+func (s *Store) CreateWithID(id string, docBytes []byte, fields []string) (*models.Session, error) {
+	// Infer field types and interview sections
 	fieldTypes := make(map[string]string)
+	typeReasons := make(map[string]string)
+	fieldGroups := make(map[string]string)
 	for _, field := range fields {
-		fieldTypes[field] = utils.InferFieldType(field)
+		inferred := utils.ExplainFieldType(field)
+		fieldTypes[field] = inferred.Type
+		typeReasons[field] = inferred.Reason
+		fieldGroups[field] = utils.InferFieldGroup(field)
+	}
+
+	ownerToken, err := NewID()
+	if err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
 	session := &models.Session{
-		ID:          id,
-		OriginalDoc: docBytes,
-		Fields:      fields,
-		FieldTypes:  fieldTypes,
-		Answers:     make(map[string]string),
-		Questions:   make(map[string]string),
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:               id,
+		OriginalDoc:      docBytes,
+		OwnerToken:       ownerToken,
+		Collaborators:    make(map[string]models.Collaborator),
+		Fields:           utils.GroupFields(fields, fieldGroups),
+		FieldTypes:       fieldTypes,
+		FieldTypeReasons: typeReasons,
+		FieldGroups:      fieldGroups,
+		Formats:          make(map[string]string),
+		FieldOptions:     make(map[string][]string),
+		Answers:          make(map[string]string),
+		Questions:        make(map[string]string),
+		PromptVersions:   make(map[string]string),
+		Proposals:        make(map[string]models.AnswerCandidate),
+		Skipped:          make(map[string]bool),
+		Optional:         make(map[string]bool),
+		Conditions:       make(map[string]models.Condition),
+		Computed:         make(map[string]string),
+		Aliases:          make(map[string]string),
+		Parties:          make(map[string]string),
+		Placeholders:     make(map[string]string),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 
 	s.mu.Lock()
@@ -60,7 +96,8 @@ func (s *Store) Create(docBytes []byte, fields []string) (*models.Session, error
 	return session, nil
 }
 
-// Get retrieves a session by ID
+// Get retrieves a session by ID. The session is shared, not copied: code that runs
+// alongside other requests, such as a stream or a background job, reads it with View.
 func (s *Store) Get(id string) (*models.Session, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -73,7 +110,23 @@ func (s *Store) Get(id string) (*models.Session, error) {
 	return session, nil
 }
 
-// Update updates a session (used for adding answers, questions)
+// View calls fn with the session locked for reading, so it sees no update half done.
+// fn must not modify the session or keep references into it.
+func (s *Store) View(id string, fn func(*models.Session)) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[id]
+	if !exists {
+		return ErrSessionNotFound
+	}
+
+	fn(session)
+	return nil
+}
+
+// Update updates a session (used for adding answers, questions). Answer changes
+// and events queued with Notify are then published to the session's watchers.
 func (s *Store) Update(id string, updateFn func(*models.Session)) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -83,8 +136,11 @@ func (s *Store) Update(id string, updateFn func(*models.Session)) error {
 		return ErrSessionNotFound
 	}
 
+	before, wasComplete := len(session.History), isComplete(session)
 	updateFn(session)
+	groupChanges(session, before, 0)
 	session.UpdatedAt = time.Now()
+	s.publish(session, collectEvents(session, before, wasComplete))
 
 	return nil
 }
@@ -99,11 +155,12 @@ func (s *Store) Delete(id string) error {
 	}
 
 	delete(s.sessions, id)
+	s.closeWatchers(id)
 	return nil
 }
 
-// generateID creates a random session ID
-func generateID() (string, error) {
+// NewID creates a random session ID
+func NewID() (string, error) {
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
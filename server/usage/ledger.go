@@ -0,0 +1,240 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/ai"
+	"github.com/you/lexsy-mvp/server/models"
+)
+
+var (
+	ErrBudgetExceeded = errors.New("AI budget exceeded")
+)
+
+// Record is a single metered AI call
+type Record struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"sessionId"`
+	Template         string    `json:"template"`
+	Operation        string    `json:"operation"` // detect, map, questions, ...
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	CostUSD          float64   `json:"costUsd"`
+}
+
+// Ledger is a thread-safe in-memory record of AI usage with optional budgets
+type Ledger struct {
+	mu            sync.RWMutex
+	records       []Record
+	prices        map[string]Price
+	sessionBudget float64            // Default per-session limit in USD, 0 = unlimited
+	dailyBudget   float64            // Server-wide limit per UTC day in USD, 0 = unlimited
+	sessionLimits map[string]float64 // Per-session overrides
+}
+
+// NewLedger creates a ledger with budgets from AI_BUDGET_SESSION_USD and AI_BUDGET_DAILY_USD
+func NewLedger() *Ledger {
+	return &Ledger{
+		prices:        loadPrices(),
+		sessionBudget: envFloat("AI_BUDGET_SESSION_USD"),
+		dailyBudget:   envFloat("AI_BUDGET_DAILY_USD"),
+		sessionLimits: make(map[string]float64),
+	}
+}
+
+// Meter wraps client so every call is checked against budgets and recorded
+func (l *Ledger) Meter(client ai.Client, sessionID, template, operation string) ai.Client {
+	return &meteredClient{Client: client, ledger: l, sessionID: sessionID, template: template, operation: operation}
+}
+
+// SetSessionBudget lowers one session's limit below the default; 0 restores the
+// default. A limit above AI_BUDGET_SESSION_USD is clamped to it. Returns the limit
+// now in force, 0 meaning unlimited.
+func (l *Ledger) SetSessionBudget(sessionID string, limitUSD float64) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limitUSD <= 0 || (l.sessionBudget > 0 && limitUSD >= l.sessionBudget) {
+		delete(l.sessionLimits, sessionID)
+		return l.sessionBudget
+	}
+	l.sessionLimits[sessionID] = limitUSD
+	return limitUSD
+}
+
+// SessionTotals returns usage attributed to a session
+func (l *Ledger) SessionTotals(sessionID string) models.UsageTotals {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var t models.UsageTotals
+	for _, r := range l.records {
+		if r.SessionID == sessionID {
+			add(&t, r)
+		}
+	}
+	return t
+}
+
+// Summary aggregates all recorded usage. It leaves out per-session totals: session IDs
+// grant access, so those are only served with the session itself.
+func (l *Ledger) Summary() models.UsageResponse {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	resp := models.UsageResponse{
+		ByProvider:       make(map[string]models.UsageTotals),
+		ByTemplate:       make(map[string]models.UsageTotals),
+		SessionBudgetUSD: l.sessionBudget,
+		DailyBudgetUSD:   l.dailyBudget,
+	}
+
+	today := dayOf(time.Now())
+	for _, r := range l.records {
+		add(&resp.Total, r)
+		if dayOf(r.Time) == today {
+			add(&resp.Today, r)
+		}
+		addTo(resp.ByProvider, r.Provider, r)
+		addTo(resp.ByTemplate, r.Template, r)
+	}
+	return resp
+}
+
+// checkBudget returns ErrBudgetExceeded if the session or today's spend has reached its limit
+func (l *Ledger) checkBudget(sessionID string) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	sessionLimit := l.sessionBudget
+	if limit, ok := l.sessionLimits[sessionID]; ok {
+		sessionLimit = limit
+	}
+	if sessionLimit <= 0 && l.dailyBudget <= 0 {
+		return nil
+	}
+
+	var sessionCost, dayCost float64
+	today := dayOf(time.Now())
+	for _, r := range l.records {
+		if r.SessionID == sessionID {
+			sessionCost += r.CostUSD
+		}
+		if dayOf(r.Time) == today {
+			dayCost += r.CostUSD
+		}
+	}
+
+	if sessionLimit > 0 && sessionCost >= sessionLimit {
+		return fmt.Errorf("%w: session spent $%.4f of $%.2f", ErrBudgetExceeded, sessionCost, sessionLimit)
+	}
+	if l.dailyBudget > 0 && dayCost >= l.dailyBudget {
+		return fmt.Errorf("%w: spent $%.4f of $%.2f today", ErrBudgetExceeded, dayCost, l.dailyBudget)
+	}
+	return nil
+}
+
+func (l *Ledger) record(r Record) {
+	l.mu.Lock()
+	l.records = append(l.records, r)
+	l.mu.Unlock()
+}
+
+// meteredClient records usage for every completion
+type meteredClient struct {
+	ai.Client
+	ledger    *Ledger
+	sessionID string
+	template  string
+	operation string
+}
+
+func (m *meteredClient) Complete(req ai.Request) (*ai.Response, error) {
+	if err := m.ledger.checkBudget(m.sessionID); err != nil {
+		return nil, err
+	}
+
+	resp, err := m.Client.Complete(req)
+	if err != nil {
+		return nil, err
+	}
+	m.record(req, resp)
+	return resp, nil
+}
+
+// Stream checks the budget and records usage like Complete, streaming when the client can
+func (m *meteredClient) Stream(req ai.Request, onText func(text string)) (*ai.Response, error) {
+	if err := m.ledger.checkBudget(m.sessionID); err != nil {
+		return nil, err
+	}
+
+	resp, err := ai.Stream(m.Client, req, onText)
+	if err != nil {
+		return nil, err
+	}
+	m.record(req, resp)
+	return resp, nil
+}
+
+// record adds a completion's usage to the ledger
+func (m *meteredClient) record(req ai.Request, resp *ai.Response) {
+	// Some self-hosted servers omit usage; estimate from text length instead
+	u := resp.Usage
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		u.PromptTokens = estimateTokens(req.System + req.Prompt)
+		u.CompletionTokens = estimateTokens(resp.Text)
+	}
+
+	m.ledger.record(Record{
+		Time:             time.Now(),
+		SessionID:        m.sessionID,
+		Template:         m.template,
+		Operation:        m.operation,
+		Provider:         string(resp.Provider),
+		Model:            resp.Model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CostUSD:          m.ledger.estimateCost(resp.Provider, resp.Model, u),
+	})
+}
+
+func add(t *models.UsageTotals, r Record) {
+	t.Calls++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.CostUSD += r.CostUSD
+}
+
+func addTo(m map[string]models.UsageTotals, key string, r Record) {
+	if key == "" {
+		return
+	}
+	t := m[key]
+	add(&t, r)
+	m[key] = t
+}
+
+func dayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func envFloat(name string) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid %s: %v", name, err)
+		return 0
+	}
+	return v
+}
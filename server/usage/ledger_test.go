@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/ai"
+)
+
+// fakeClient returns a fixed response with known token counts
+type fakeClient struct{}
+
+func (fakeClient) Provider() ai.Provider { return ai.ProviderOpenAI }
+func (fakeClient) Model() string         { return "gpt-4" }
+func (fakeClient) Complete(ai.Request) (*ai.Response, error) {
+	return &ai.Response{
+		Text:     "{}",
+		Provider: ai.ProviderOpenAI,
+		Model:    "gpt-4",
+		Usage:    ai.Usage{PromptTokens: 1000, CompletionTokens: 500},
+	}, nil
+}
+
+// TestLedgerMeterAndBudget tests cost attribution and per-session budget enforcement
+func TestLedgerMeterAndBudget(t *testing.T) {
+	ledger := NewLedger()
+	assert.Equal(t, 0.05, ledger.SetSessionBudget("s1", 0.05))
+	client := ledger.Meter(fakeClient{}, "s1", "safe.docx", "questions")
+
+	_, err := client.Complete(ai.Request{Prompt: "hello"})
+	require.NoError(t, err)
+
+	totals := ledger.SessionTotals("s1")
+	assert.Equal(t, 1, totals.Calls)
+	assert.Equal(t, 1000, totals.PromptTokens)
+	assert.InDelta(t, 0.06, totals.CostUSD, 1e-9) // 1000*$30/M + 500*$60/M
+
+	summary := ledger.Summary()
+	assert.Equal(t, 1, summary.ByTemplate["safe.docx"].Calls)
+	assert.Equal(t, 1, summary.ByProvider["openai"].Calls)
+	assert.Equal(t, 1, summary.Total.Calls)
+
+	// $0.06 spent against a $0.05 limit blocks further calls
+	_, err = client.Complete(ai.Request{Prompt: "hello"})
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	// Other sessions are unaffected
+	_, err = ledger.Meter(fakeClient{}, "s2", "safe.docx", "questions").Complete(ai.Request{Prompt: "hello"})
+	assert.NoError(t, err)
+}
+
+// TestSetSessionBudgetOnlyLowers tests that a session's limit can't go above the server's
+func TestSetSessionBudgetOnlyLowers(t *testing.T) {
+	t.Setenv("AI_BUDGET_SESSION_USD", "1")
+	ledger := NewLedger()
+	tests := []struct {
+		limit float64
+		want  float64
+	}{
+		{0.5, 0.5},
+		{5, 1}, // Clamped to the server's limit
+		{1, 1},
+		{0, 1}, // Back to the default
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ledger.SetSessionBudget("s1", tt.limit), tt.limit)
+	}
+}
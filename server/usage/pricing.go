@@ -0,0 +1,64 @@
+package usage
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/you/lexsy-mvp/server/ai"
+)
+
+// Price is the USD cost per million tokens for a model
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// defaultPrices are list prices at the time of writing; override with AI_PRICING
+var defaultPrices = map[string]Price{
+	"gpt-4":            {Prompt: 30, Completion: 60},
+	"gpt-4o":           {Prompt: 2.5, Completion: 10},
+	"gpt-4o-mini":      {Prompt: 0.15, Completion: 0.6},
+	"gemini-2.0-flash": {Prompt: 0.10, Completion: 0.40},
+	"gemini-1.5-pro":   {Prompt: 1.25, Completion: 5},
+}
+
+// loadPrices returns the default price table merged with AI_PRICING, a JSON
+// object such as {"gpt-4o": {"prompt": 2.5, "completion": 10}}
+func loadPrices() map[string]Price {
+	prices := make(map[string]Price, len(defaultPrices))
+	for model, p := range defaultPrices {
+		prices[model] = p
+	}
+
+	if raw := os.Getenv("AI_PRICING"); raw != "" {
+		var overrides map[string]Price
+		if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+			log.Printf("Ignoring invalid AI_PRICING: %v", err)
+		}
+		for model, p := range overrides {
+			prices[strings.ToLower(model)] = p
+		}
+	}
+
+	return prices
+}
+
+// estimateCost prices a call. Self-hosted models are free; unknown models cost nothing
+// rather than guessing.
+func (l *Ledger) estimateCost(provider ai.Provider, model string, u ai.Usage) float64 {
+	if provider == ai.ProviderLocal {
+		return 0
+	}
+	p, ok := l.prices[strings.ToLower(model)]
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion) / 1_000_000
+}
+
+// estimateTokens approximates token count for providers that don't report usage (~4 chars per token)
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
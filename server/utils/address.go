@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Address formats for the document
+const (
+	AddressSingleLine = "single_line" // "1 Main St, Springfield, IL 62701"
+	AddressMultiLine  = "multi_line"  // Street, city line and country on separate lines
+)
+
+// AddressFormats lists the accepted address formats, the first being the default
+var AddressFormats = []string{AddressSingleLine, AddressMultiLine}
+
+// Address is a structured address answer, stored as JSON
+type Address struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"` // State, province or county
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// AddressPart describes one sub-answer of an address for the interview
+type AddressPart struct {
+	Name     string
+	Label    string
+	Required bool
+}
+
+// AddressParts are the sub-answers collected for address fields, in order
+var AddressParts = []AddressPart{
+	{"street", "Street", true},
+	{"city", "City", true},
+	{"region", "State / region", false},
+	{"postalCode", "Postal code", false},
+	{"country", "Country", false},
+}
+
+// countries maps lowercase names and unambiguous codes to the country's name.
+// Two-letter codes that are also US states (CA, DE, IN, ...) are left out.
+var countries = map[string]string{
+	"united states": "United States", "united states of america": "United States", "usa": "United States", "us": "United States", "u.s.": "United States", "u.s.a.": "United States",
+	"canada":         "Canada",
+	"united kingdom": "United Kingdom", "uk": "United Kingdom", "u.k.": "United Kingdom", "gb": "United Kingdom", "great britain": "United Kingdom", "england": "United Kingdom", "scotland": "United Kingdom", "wales": "United Kingdom",
+	"germany": "Germany", "france": "France", "spain": "Spain", "italy": "Italy", "netherlands": "Netherlands", "the netherlands": "Netherlands",
+	"australia": "Australia", "new zealand": "New Zealand", "switzerland": "Switzerland", "austria": "Austria", "belgium": "Belgium",
+	"denmark": "Denmark", "norway": "Norway", "india": "India", "singapore": "Singapore", "japan": "Japan", "ireland": "Ireland",
+}
+
+// postalFormat validates and normalizes a country's postal codes
+type postalFormat struct {
+	pattern *regexp.Regexp
+	example string
+	format  func(string) string // Optional canonical form, applied after matching
+}
+
+// spaceBeforeLast3 writes UK and Canadian postcodes as "SW1A 1AA" / "K1A 0B1"
+func spaceBeforeLast3(code string) string {
+	code = strings.ReplaceAll(code, " ", "")
+	return code[:len(code)-3] + " " + code[len(code)-3:]
+}
+
+var (
+	fiveDigits = postalFormat{pattern: regexp.MustCompile(`^\d{5}$`), example: "75008"}
+	fourDigits = postalFormat{pattern: regexp.MustCompile(`^\d{4}$`), example: "2000"}
+	sixDigits  = postalFormat{pattern: regexp.MustCompile(`^\d{6}$`), example: "560001"}
+)
+
+// postalFormats are the postal code rules per country; other countries accept any code
+var postalFormats = map[string]postalFormat{
+	"United States":  {pattern: regexp.MustCompile(`^\d{5}(-\d{4})?$`), example: "62701 or 62701-1234"},
+	"Canada":         {pattern: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), example: "K1A 0B1", format: spaceBeforeLast3},
+	"United Kingdom": {pattern: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`), example: "SW1A 1AA", format: spaceBeforeLast3},
+	"Netherlands":    {pattern: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`), example: "1012 AB", format: spaceBeforeLast2},
+	"Japan":          {pattern: regexp.MustCompile(`^\d{3}-?\d{4}$`), example: "100-0001"},
+	"Germany":        fiveDigits, "France": fiveDigits, "Spain": fiveDigits, "Italy": fiveDigits,
+	"Australia": fourDigits, "New Zealand": fourDigits, "Switzerland": fourDigits, "Austria": fourDigits,
+	"Belgium": fourDigits, "Denmark": fourDigits, "Norway": fourDigits,
+	"India": sixDigits, "Singapore": sixDigits,
+}
+
+// spaceBeforeLast2 writes Dutch postcodes as "1012 AB"
+func spaceBeforeLast2(code string) string {
+	code = strings.ReplaceAll(code, " ", "")
+	return code[:len(code)-2] + " " + code[len(code)-2:]
+}
+
+var (
+	// "IL 62701" or "Illinois 62701-1234" at the end of a US address
+	usRegionZip = regexp.MustCompile(`^([A-Za-z][A-Za-z .]*?)\s+(\d{5}(?:-\d{4})?)$`)
+	// "Ontario K1A 0B1", "London SW1A 1AA" or a bare postcode
+	trailingPostcode = regexp.MustCompile(`(?i)^(.*?)\s*\b([A-Z]\d[A-Z] ?\d[A-Z]\d|[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2})$`)
+	// "10115 Berlin" or "1012 AB Amsterdam"
+	leadingPostcode = regexp.MustCompile(`(?i)^(\d{4,5}(?: ?[A-Z]{2}\b)?)\s+(.+)$`)
+)
+
+// ParseAddress reads an address answer: a JSON object of parts ({"street": ..., "city": ...})
+// or free text such as "1 Main St, Springfield, IL 62701". The result is validated.
+func ParseAddress(value string) (Address, error) {
+	var addr Address
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &addr); err != nil {
+			return Address{}, fmt.Errorf("%w: address parts must be a JSON object of strings", ErrInvalidValue)
+		}
+	} else {
+		addr = parseAddressText(value)
+	}
+	return addr.normalize()
+}
+
+// parseAddressText splits a free-text address into parts, working back from the end:
+// country, then postal code and region, then city; what's left is the street
+func parseAddressText(value string) Address {
+	var segments []string
+	for _, line := range strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n") {
+		for _, seg := range strings.Split(line, ",") {
+			if seg = strings.TrimSpace(seg); seg != "" {
+				segments = append(segments, seg)
+			}
+		}
+	}
+
+	var addr Address
+	last := func() string { return segments[len(segments)-1] }
+	pop := func() { segments = segments[:len(segments)-1] }
+
+	if len(segments) > 2 {
+		if country, ok := countries[strings.ToLower(last())]; ok {
+			addr.Country = country
+			pop()
+		}
+	}
+
+	if len(segments) > 1 {
+		tail := last()
+		if region, city, zip, ok := splitUSTail(tail); ok {
+			addr.Region, addr.City, addr.PostalCode = region, city, zip
+			pop()
+		} else if _, ok := usStates[strings.ToLower(tail)]; ok && len(segments) > 2 {
+			addr.Region = tail
+			pop()
+		} else if m := leadingPostcode.FindStringSubmatch(tail); m != nil {
+			addr.PostalCode, addr.City = m[1], m[2]
+			pop()
+		} else if m := trailingPostcode.FindStringSubmatch(tail); m != nil && hasDigit(m[2]) {
+			addr.PostalCode = m[2]
+			pop()
+			// "London SW1A 1AA" names the city unless a separate city segment is left
+			if m[1] != "" && len(segments) > 1 {
+				addr.Region = m[1]
+			} else if m[1] != "" {
+				addr.City = m[1]
+			}
+		}
+	}
+
+	if addr.City == "" && len(segments) > 1 {
+		addr.City = last()
+		pop()
+	}
+	addr.Street = strings.Join(segments, ", ")
+	return addr
+}
+
+// splitUSTail reads "IL 62701", "Illinois 62701" or "Springfield IL 62701-1234"
+func splitUSTail(tail string) (region, city, zip string, ok bool) {
+	m := usRegionZip.FindStringSubmatch(tail)
+	if m == nil {
+		return "", "", "", false
+	}
+	region, zip = m[1], m[2]
+	if _, ok := usStates[strings.ToLower(region)]; ok {
+		return region, "", zip, true
+	}
+	if i := strings.LastIndex(region, " "); i > 0 {
+		if _, ok := usStates[strings.ToLower(region[i+1:])]; ok {
+			return region[i+1:], strings.TrimSpace(region[:i]), zip, true
+		}
+	}
+	return "", "", "", false
+}
+
+// normalize trims the parts, spells out the country, writes US states as their postal
+// abbreviation and checks the postal code against the country's format
+func (a Address) normalize() (Address, error) {
+	for _, part := range []*string{&a.Street, &a.City, &a.Region, &a.PostalCode, &a.Country} {
+		*part = whitespace.ReplaceAllString(strings.TrimSpace(*part), " ")
+	}
+	if a.Street == "" || a.City == "" {
+		return Address{}, fmt.Errorf("%w: an address needs at least a street and a city", ErrInvalidValue)
+	}
+
+	if name, ok := countries[strings.ToLower(a.Country)]; ok {
+		a.Country = name
+	} else if strings.EqualFold(a.Country, "CA") {
+		a.Country = "Canada"
+	}
+
+	country := a.Country
+	if state, ok := usStates[strings.ToLower(a.Region)]; ok && (country == "" || country == "United States") {
+		a.Region = stateAbbreviation(state)
+		country = "United States"
+	}
+
+	if a.PostalCode != "" {
+		a.PostalCode = strings.ToUpper(a.PostalCode)
+		if rule, ok := postalFormats[country]; ok {
+			if !rule.pattern.MatchString(a.PostalCode) {
+				return Address{}, fmt.Errorf("%w: %q is not a valid %s postal code (e.g. %s)", ErrInvalidValue, a.PostalCode, country, rule.example)
+			}
+			if rule.format != nil {
+				a.PostalCode = rule.format(a.PostalCode)
+			}
+		}
+	}
+	return a, nil
+}
+
+// Format writes the address for the document in one of AddressFormats
+func (a Address) Format(format string) string {
+	cityLine := a.City
+	if tail := strings.TrimSpace(a.Region + " " + a.PostalCode); tail != "" {
+		cityLine += ", " + tail
+	}
+
+	lines := []string{a.Street, cityLine}
+	if a.Country != "" {
+		lines = append(lines, a.Country)
+	}
+	if format == AddressMultiLine {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines, ", ")
+}
+
+// IsAddressFormat reports whether format is one of AddressFormats
+func IsAddressFormat(format string) bool {
+	for _, f := range AddressFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// stateAbbreviation returns the postal abbreviation for a US state's name
+func stateAbbreviation(name string) string {
+	for key, state := range usStates {
+		if state == name && len(key) == 2 {
+			return strings.ToUpper(key)
+		}
+	}
+	return name
+}
+
+func hasDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789")
+}
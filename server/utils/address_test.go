@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAddress tests splitting free-text and structured addresses into parts
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Address
+	}{
+		{"1 Main St, Springfield, IL 62701", Address{"1 Main St", "Springfield", "IL", "62701", ""}},
+		{"1 Main St\nSuite 200\nSpringfield Illinois 62701-1234\nUSA", Address{"1 Main St, Suite 200", "Springfield", "IL", "62701-1234", "United States"}},
+		{"10 Downing St, London SW1A2AA, UK", Address{"10 Downing St", "London", "", "SW1A 2AA", "United Kingdom"}},
+		{"24 Sussex Dr, Ottawa, Ontario K1M 1M4, Canada", Address{"24 Sussex Dr", "Ottawa", "Ontario", "K1M 1M4", "Canada"}},
+		{"Unter den Linden 77, 10117 Berlin, Germany", Address{"Unter den Linden 77", "Berlin", "", "10117", "Germany"}},
+		{`{"street": "1 Main St", "city": "Springfield", "region": "illinois", "postalCode": "62701"}`, Address{"1 Main St", "Springfield", "IL", "62701", ""}},
+		{`{"street": "1 Rue de Rivoli", "city": "Paris", "postalCode": "75001", "country": "france"}`, Address{"1 Rue de Rivoli", "Paris", "", "75001", "France"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+// TestParseAddressValidation tests required parts and postal codes per country
+func TestParseAddressValidation(t *testing.T) {
+	for _, input := range []string{
+		"1 Main St",
+		`{"street": "1 Main St"}`,
+		`{"street": "1 Main St", "city": "Springfield", "region": "IL", "postalCode": "6270"}`,
+		`{"street": "10 Downing St", "city": "London", "postalCode": "12345", "country": "UK"}`,
+		`{"street": "1 Rue de Rivoli", "city": "Paris", "postalCode": "750", "country": "France"}`,
+		`["1 Main St"]`,
+	} {
+		_, err := ParseAddress(input)
+		assert.ErrorIs(t, err, ErrInvalidValue, input)
+	}
+
+	// Countries without a known format accept any postal code
+	_, err := ParseAddress(`{"street": "Av. Paulista 1578", "city": "São Paulo", "postalCode": "01310-200", "country": "Brazil"}`)
+	assert.NoError(t, err)
+}
+
+// TestAddressFormat tests the single-line and multi-line document layouts
+func TestAddressFormat(t *testing.T) {
+	addr := Address{"1 Main St", "Springfield", "IL", "62701", "United States"}
+
+	assert.Equal(t, "1 Main St, Springfield, IL 62701, United States", addr.Format(AddressSingleLine))
+	assert.Equal(t, "1 Main St\nSpringfield, IL 62701\nUnited States", addr.Format(AddressMultiLine))
+	assert.Equal(t, "1 Main St, Springfield", Address{Street: "1 Main St", City: "Springfield"}.Format(""))
+}
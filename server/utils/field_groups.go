@@ -0,0 +1,63 @@
+package utils
+
+import "strings"
+
+// Interview sections, so the UI can show headers
+const (
+	GroupParties    = "parties"
+	GroupEconomics  = "economics"
+	GroupDates      = "dates"
+	GroupSignatures = "signatures"
+	GroupOther      = "other"
+)
+
+// fieldGroupWords maps name words to groups, checked in order so that
+// "investor_signature" is a signature rather than a party
+var fieldGroupWords = []struct {
+	group string
+	words []string
+}{
+	{GroupSignatures, []string{"signature", "signatory", "signer", "sign", "signed", "by", "title", "witness"}},
+	{GroupDates, []string{"date", "dob", "day", "days", "month", "months", "year", "years", "term", "deadline", "expiry", "expiration"}},
+	{GroupEconomics, []string{"amount", "price", "cap", "valuation", "discount", "rate", "rent", "salary", "fee", "fees", "deposit", "payment", "total", "compensation", "bonus", "shares", "equity", "interest", "percentage"}},
+	{GroupParties, []string{"name", "company", "investor", "party", "employer", "employee", "tenant", "landlord", "client", "vendor", "buyer", "seller", "purchaser", "address", "email", "phone", "state", "jurisdiction"}},
+}
+
+// InferFieldGroup determines the interview section based on the words in the field name
+// Returns: "parties", "economics", "dates", "signatures", or "other"
+func InferFieldGroup(fieldName string) string {
+	words := strings.FieldsFunc(strings.ToLower(fieldName), func(r rune) bool {
+		return r == '_' || r == ' ' || r == '-'
+	})
+
+	for _, entry := range fieldGroupWords {
+		for _, keyword := range entry.words {
+			for _, word := range words {
+				if word == keyword {
+					return entry.group
+				}
+			}
+		}
+	}
+	return GroupOther
+}
+
+// GroupFields keeps each section's fields together for the interview. Sections appear
+// in the order their first field does, and fields keep their order within a section.
+func GroupFields(fields []string, groups map[string]string) []string {
+	var order []string
+	bySection := make(map[string][]string)
+	for _, field := range fields {
+		group := groups[field]
+		if _, seen := bySection[group]; !seen {
+			order = append(order, group)
+		}
+		bySection[group] = append(bySection[group], field)
+	}
+
+	grouped := make([]string, 0, len(fields))
+	for _, group := range order {
+		grouped = append(grouped, bySection[group]...)
+	}
+	return grouped
+}
@@ -1,35 +1,261 @@
 package utils
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field types
+const (
+	TypeText         = "text"
+	TypeNumber       = "number"
+	TypeDate         = "date"
+	TypeCurrency     = "currency"
+	TypePercent      = "percent"
+	TypeEmail        = "email"
+	TypePhone        = "phone"
+	TypeAddress      = "address"
+	TypeBoolean      = "boolean"
+	TypeSelect       = "select"
+	TypeMultiline    = "multiline"
+	TypeSignature    = "signature"
+	TypeJurisdiction = "jurisdiction"
+	TypeEntityType   = "entity_type"
+)
+
+// FieldTypeNames lists the input types a field can have
+var FieldTypeNames = []string{
+	TypeText, TypeNumber, TypeDate, TypeCurrency, TypePercent, TypeEmail, TypePhone, TypeAddress,
+	TypeBoolean, TypeSelect, TypeMultiline, TypeSignature, TypeJurisdiction, TypeEntityType,
+}
+
+// IsFieldType reports whether t is a known field type
+func IsFieldType(t string) bool {
+	for _, name := range FieldTypeNames {
+		if name == t {
+			return true
+		}
+	}
+	return false
+}
+
+// booleanPrefixes mark yes/no questions such as has_discount or is_exclusive
+var booleanPrefixes = []string{"is", "has", "does", "will", "includes", "include", "allow", "allows"}
+
+// Sources of an inferred type, from strongest to weakest
+const (
+	SourceContext    = "context"    // Text around the placeholder in the document
+	SourceDictionary = "dictionary" // FIELD_TYPE_DICTIONARY
+	SourceProfile    = "profile"    // Document type defaults
+	SourceName       = "name"       // Built-in keywords in the field name
+	SourceDefault    = "default"    // Nothing matched
+)
+
+// TypeInference is an inferred type with where it came from and why it was chosen
+type TypeInference struct {
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// typeKeywords is the built-in dictionary, checked in order; the first type with a
+// keyword in the field name wins, so "email_address" is an email rather than an address.
+// Keywords match whole words of the name; multi-word keywords match consecutive words.
+var typeKeywords = []struct {
+	fieldType string
+	keywords  []string
+}{
+	{TypeSignature, []string{"signature", "esign", "esignature"}},
+	{TypeEmail, []string{"email", "e_mail"}},
+	{TypePhone, []string{"phone", "telephone", "mobile", "fax", "cell"}},
+	{TypeAddress, []string{"address", "street", "mailing"}},
+	{TypeDate, []string{"date", "dated", "dob", "birthday", "birth", "deadline", "expiry", "expiration", "anniversary"}},
+	{TypeMultiline, []string{"description", "notes", "comments", "details", "purpose", "scope", "remarks", "statement", "terms", "recitals"}},
+	{TypeEntityType, []string{"entity_type", "entity_form", "company_type", "business_type", "legal_form", "form_of_entity"}},
+	{TypeJurisdiction, []string{"jurisdiction", "governing_law", "state", "country", "venue", "province"}},
+	{TypePercent, []string{"percent", "percentage", "pct", "interest_rate", "tax_rate", "discount_rate", "commission_rate", "growth_rate"}},
+	{TypeCurrency, []string{"amount", "price", "salary", "fee", "fees", "rent", "deposit", "valuation", "cap", "cost", "payment", "compensation", "bonus", "subtotal", "consideration", "retainer"}},
+	{TypeNumber, []string{"age", "count", "number", "quantity", "qty", "total", "sum", "year", "years", "month", "months", "days", "hours", "shares"}},
+}
+
+var (
+	dictionaryMu sync.RWMutex
+
+	// customKeywords come from FIELD_TYPE_DICTIONARY and are checked before the built-in ones
+	customKeywords map[string]string
+	// customOrder lists customKeywords longest first so "closing_date" beats "date"
+	customOrder []string
+)
+
+// LoadTypeDictionary reads a JSON file of extra keywords per type, e.g.
+// {"currency": ["retainer", "consideration"], "date": ["closing"]}. Its keywords take
+// precedence over the built-in dictionary. An empty path clears the custom dictionary.
+func LoadTypeDictionary(path string) error {
+	keywords := make(map[string]string)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var byType map[string][]string
+		if err := json.Unmarshal(data, &byType); err != nil {
+			return fmt.Errorf("failed to parse field type dictionary %s: %w", path, err)
+		}
+		for fieldType, words := range byType {
+			if !IsFieldType(fieldType) {
+				return fmt.Errorf("field type dictionary %s: unknown type %q", path, fieldType)
+			}
+			for _, word := range words {
+				keywords[strings.ToLower(strings.ReplaceAll(strings.TrimSpace(word), " ", "_"))] = fieldType
+			}
+		}
+	}
+
+	order := make([]string, 0, len(keywords))
+	for keyword := range keywords {
+		order = append(order, keyword)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if len(order[i]) != len(order[j]) {
+			return len(order[i]) > len(order[j])
+		}
+		return order[i] < order[j]
+	})
+
+	dictionaryMu.Lock()
+	customKeywords, customOrder = keywords, order
+	dictionaryMu.Unlock()
+	return nil
+}
 
 // InferFieldType determines the input type based on the field name
-// Returns: "text", "number", or "date"
+// Returns one of FieldTypeNames other than "select", which needs options; "text" by default
 func InferFieldType(fieldName string) string {
-	lowerField := strings.ToLower(fieldName)
+	return ExplainFieldType(fieldName).Type
+}
 
-	// Date patterns
-	datePatterns := []string{
-		"date", "dob", "birth", "deadline",
-		"expiry", "expiration", "anniversary",
-	}
-	for _, pattern := range datePatterns {
-		if strings.Contains(lowerField, pattern) {
-			return "date"
+// ExplainFieldType infers a type from the words of the field name and says why
+func ExplainFieldType(fieldName string) TypeInference {
+	words := nameWords(fieldName)
+
+	// Yes/no questions
+	if len(words) > 1 {
+		for _, prefix := range booleanPrefixes {
+			if words[0] == prefix {
+				return TypeInference{TypeBoolean, SourceName, fmt.Sprintf("name starts with %q, a yes/no question", prefix)}
+			}
 		}
 	}
 
-	// Number patterns
-	numberPatterns := []string{
-		"age", "count", "number", "amount",
-		"quantity", "price", "total", "sum",
-		"year", "months", "days", "hours",
+	dictionaryMu.RLock()
+	custom, order := customKeywords, customOrder
+	dictionaryMu.RUnlock()
+	for _, keyword := range order {
+		if hasWords(words, keyword) {
+			return TypeInference{custom[keyword], SourceDictionary, fmt.Sprintf("%q is listed as %s in the field type dictionary", keyword, custom[keyword])}
+		}
 	}
-	for _, pattern := range numberPatterns {
-		if strings.Contains(lowerField, pattern) {
-			return "number"
+
+	// invoice_number or case_number is an identifier such as "INV-2024-001", not an amount
+	identifier := len(words) > 1 && words[len(words)-1] == "number"
+	for _, entry := range typeKeywords {
+		if identifier && (entry.fieldType == TypeNumber || entry.fieldType == TypeCurrency || entry.fieldType == TypePercent) {
+			continue
+		}
+		for _, keyword := range entry.keywords {
+			if hasWords(words, keyword) {
+				return TypeInference{entry.fieldType, SourceName, fmt.Sprintf("name contains the word %q", strings.ReplaceAll(keyword, "_", " "))}
+			}
 		}
 	}
+	if identifier {
+		return TypeInference{TypeText, SourceName, `name ends in "number", so it's an identifier`}
+	}
 
 	// Default to text
-	return "text"
+	return TypeInference{TypeText, SourceDefault, "no type keywords in the name"}
+}
+
+// contextClues are phrases around a placeholder that give its type away
+var contextClues = []struct {
+	fieldType string
+	before    []string // Text immediately before the placeholder
+	after     []string // Text immediately after the placeholder
+}{
+	{TypeCurrency, []string{"$", "usd", "us$", "€", "£"}, []string{"dollars", "usd"}},
+	{TypePercent, nil, []string{"%", "percent", "per cent"}},
+	{TypeDate, []string{"dated as of", "effective as of", "as of", "dated", "on or before", "no later than", "entered into on", "commencing on", "made on"}, nil},
+	{TypeSignature, []string{"signature:", "signed:", "/s/"}, nil},
+	{TypeEmail, []string{"email:", "e-mail:"}, nil},
+	{TypePhone, []string{"phone:", "telephone:", "tel:"}, nil},
+	{TypeJurisdiction, []string{"laws of the state of", "state of", "laws of"}, nil},
+}
+
+// InferFieldTypeInContext uses the document text around the placeholder before
+// falling back to the field name: a leading "$" means currency, a trailing "%" means
+// percent, "dated as of" means date. Yes/no names are always boolean.
+func InferFieldTypeInContext(fieldName, before, after string) TypeInference {
+	byName := ExplainFieldType(fieldName)
+	if byName.Type == TypeBoolean {
+		return byName
+	}
+
+	before = strings.ToLower(strings.TrimRight(before, " \t([\"“"))
+	after = strings.ToLower(strings.TrimLeft(after, " \t)]\"”"))
+	for _, clue := range contextClues {
+		for _, phrase := range clue.before {
+			if strings.HasSuffix(before, phrase) && wordBoundary(before, len(before)-len(phrase)) {
+				return TypeInference{clue.fieldType, SourceContext, fmt.Sprintf("preceded by %q in the document", phrase)}
+			}
+		}
+		for _, phrase := range clue.after {
+			if strings.HasPrefix(after, phrase) && wordBoundary(after, len(phrase)) {
+				return TypeInference{clue.fieldType, SourceContext, fmt.Sprintf("followed by %q in the document", phrase)}
+			}
+		}
+	}
+
+	return byName
+}
+
+// wordBoundary reports whether position i of text doesn't split two letters,
+// so "dated" isn't found at the end of "outdated"
+func wordBoundary(text string, i int) bool {
+	if i <= 0 || i >= len(text) {
+		return true
+	}
+	return !isLetter(text[i-1]) || !isLetter(text[i])
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// nameWords splits a field name into lowercase words
+func nameWords(fieldName string) []string {
+	return strings.FieldsFunc(strings.ToLower(fieldName), func(r rune) bool {
+		return r == '_' || r == ' ' || r == '-'
+	})
+}
+
+// hasWords reports whether keyword's words (split on "_") appear consecutively in words
+func hasWords(words []string, keyword string) bool {
+	want := strings.Split(keyword, "_")
+	for i := 0; i+len(want) <= len(words); i++ {
+		match := true
+		for j, w := range want {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
 }
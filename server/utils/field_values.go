@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidValue = errors.New("invalid value")
+
+// DateLayout is how dates are stored; answers in other common formats are converted to it
+const DateLayout = "2006-01-02"
+
+// dateInputLayouts are the date formats accepted in answers
+var dateInputLayouts = []string{DateLayout, "January 2, 2006", "Jan 2, 2006", "January 2 2006", "01/02/2006", "1/2/2006", "2 January 2006", "02-01-2006"}
+
+var (
+	amountSuffix = regexp.MustCompile(`(?i)^([\d.]+)\s*(k|m|mm|million|b|bn|billion|thousand)$`)
+	whitespace   = regexp.MustCompile(`\s+`)
+)
+
+// usStates maps lowercase names and postal abbreviations to the state's name
+var usStates = func() map[string]string {
+	names := map[string]string{
+		"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+		"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia", "FL": "Florida",
+		"GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois", "IN": "Indiana",
+		"IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana", "ME": "Maine",
+		"MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota", "MS": "Mississippi",
+		"MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada", "NH": "New Hampshire",
+		"NJ": "New Jersey", "NM": "New Mexico", "NY": "New York", "NC": "North Carolina", "ND": "North Dakota",
+		"OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon", "PA": "Pennsylvania", "RI": "Rhode Island",
+		"SC": "South Carolina", "SD": "South Dakota", "TN": "Tennessee", "TX": "Texas", "UT": "Utah",
+		"VT": "Vermont", "VA": "Virginia", "WA": "Washington", "WV": "West Virginia", "WI": "Wisconsin",
+		"WY": "Wyoming",
+	}
+	lookup := make(map[string]string, len(names)*2)
+	for abbr, name := range names {
+		lookup[strings.ToLower(abbr)] = name
+		lookup[strings.ToLower(name)] = name
+	}
+	return lookup
+}()
+
+// entityTypes maps common spellings and abbreviations to the entity type's name
+var entityTypes = map[string]string{
+	"corporation": "corporation", "corp": "corporation", "inc": "corporation", "c corp": "corporation", "c-corp": "corporation", "s corp": "corporation",
+	"limited liability company": "limited liability company", "llc": "limited liability company",
+	"limited partnership": "limited partnership", "lp": "limited partnership",
+	"limited liability partnership": "limited liability partnership", "llp": "limited liability partnership",
+	"general partnership": "general partnership", "partnership": "general partnership", "gp": "general partnership",
+	"public benefit corporation": "public benefit corporation", "pbc": "public benefit corporation",
+	"nonprofit corporation": "nonprofit corporation", "nonprofit": "nonprofit corporation", "non-profit": "nonprofit corporation",
+	"sole proprietorship": "sole proprietorship", "sole proprietor": "sole proprietorship",
+}
+
+// DefaultOptions returns the choices offered for a type when the field has none of its own
+func DefaultOptions(fieldType string) []string {
+	switch fieldType {
+	case TypeBoolean:
+		return []string{"Yes", "No"}
+	case TypeEntityType:
+		return []string{"corporation", "limited liability company", "limited partnership", "limited liability partnership", "general partnership", "public benefit corporation", "nonprofit corporation", "sole proprietorship"}
+	}
+	return nil
+}
+
+// NormalizeValue validates an answer for its field type and converts it to the stored
+// form: amounts and percentages as plain numbers, dates as YYYY-MM-DD, booleans as
+// Yes/No, phone numbers formatted, select values matched to an option.
+func NormalizeValue(fieldType, value string, options []string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	switch fieldType {
+	case TypeNumber:
+		n, err := parseAmount(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a number", ErrInvalidValue, value)
+		}
+		return formatPlain(n), nil
+
+	case TypeCurrency:
+		n, err := parseAmount(strings.NewReplacer("$", "", "USD", "", "usd", "").Replace(value))
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not an amount", ErrInvalidValue, value)
+		}
+		return formatPlain(math.Round(n*100) / 100), nil
+
+	case TypePercent:
+		n, err := parseAmount(strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(value, "%"), " percent")))
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a percentage", ErrInvalidValue, value)
+		}
+		return formatPlain(n), nil
+
+	case TypeDate:
+		for _, layout := range dateInputLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format(DateLayout), nil
+			}
+		}
+		return "", fmt.Errorf("%w: %q is not a recognizable date (try YYYY-MM-DD)", ErrInvalidValue, value)
+
+	case TypeEmail:
+		addr, err := mail.ParseAddress(value)
+		if err != nil || !strings.Contains(addr.Address, ".") {
+			return "", fmt.Errorf("%w: %q is not an email address", ErrInvalidValue, value)
+		}
+		return addr.Address, nil
+
+	case TypePhone:
+		return normalizePhone(value)
+
+	case TypeBoolean:
+		switch strings.ToLower(value) {
+		case "yes", "y", "true", "1":
+			return "Yes", nil
+		case "no", "n", "false", "0":
+			return "No", nil
+		}
+		return "", fmt.Errorf("%w: %q is not yes or no", ErrInvalidValue, value)
+
+	case TypeSelect, TypeEntityType:
+		if len(options) == 0 {
+			options = DefaultOptions(fieldType)
+		}
+		key := strings.ToLower(strings.Trim(value, ". "))
+		if fieldType == TypeEntityType {
+			if name, ok := entityTypes[key]; ok {
+				key = name
+			}
+		}
+		for _, option := range options {
+			if strings.EqualFold(option, key) {
+				return option, nil
+			}
+		}
+		if len(options) == 0 {
+			return value, nil
+		}
+		return "", fmt.Errorf("%w: %q is not one of: %s", ErrInvalidValue, value, strings.Join(options, ", "))
+
+	case TypeJurisdiction:
+		key := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(value), "the "), "state of ")
+		if name, ok := usStates[key]; ok {
+			return name, nil
+		}
+		return value, nil
+
+	case TypeMultiline:
+		return strings.ReplaceAll(value, "\r\n", "\n"), nil
+
+	case TypeAddress:
+		addr, err := ParseAddress(value)
+		if err != nil {
+			return "", err
+		}
+		stored, _ := json.Marshal(addr)
+		return string(stored), nil
+
+	case TypeSignature:
+		return whitespace.ReplaceAllString(strings.TrimSpace(strings.TrimPrefix(value, "/s/")), " "), nil
+	}
+
+	return whitespace.ReplaceAllString(value, " "), nil
+}
+
+// RenderValue formats a stored answer for the document: amounts with thousands separators
+// and a dollar sign, percentages with %, dates written out, addresses in the field's
+// format (one of AddressFormats, "" for the default) and signatures conformed ("/s/ Jane Doe")
+func RenderValue(fieldType, value, format string) string {
+	if value == "" {
+		return ""
+	}
+
+	switch fieldType {
+	case TypeCurrency:
+		if n, err := parseAmount(value); err == nil {
+			decimals := 0
+			if n != math.Trunc(n) {
+				decimals = 2
+			}
+			return "$" + groupThousands(n, decimals)
+		}
+	case TypePercent:
+		if n, err := parseAmount(value); err == nil {
+			return formatPlain(n) + "%"
+		}
+	case TypeDate:
+		if t, err := time.Parse(DateLayout, value); err == nil {
+			return t.Format("January 2, 2006")
+		}
+	case TypeAddress:
+		if addr, err := ParseAddress(value); err == nil {
+			return addr.Format(format)
+		}
+		if format != AddressMultiLine {
+			return strings.Join(strings.Split(value, "\n"), ", ")
+		}
+	case TypeSignature:
+		return "/s/ " + value
+	}
+	return value
+}
+
+// parseAmount reads "1,500,000", "1.5m" or "500k"
+func parseAmount(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	multiplier := 1.0
+	if m := amountSuffix.FindStringSubmatch(value); m != nil {
+		value = m[1]
+		switch strings.ToLower(m[2]) {
+		case "k", "thousand":
+			multiplier = 1e3
+		case "m", "mm", "million":
+			multiplier = 1e6
+		default:
+			multiplier = 1e9
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, ErrInvalidValue
+	}
+	return n * multiplier, nil
+}
+
+// formatPlain writes a number without exponent or trailing zeros
+func formatPlain(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// groupThousands writes 1234567.5 as "1,234,567.50" for 2 decimals
+func groupThousands(n float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if n < 0 {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+	return b.String()
+}
+
+// normalizePhone formats US numbers as (555) 123-4567 and others as +<digits>
+func normalizePhone(value string) (string, error) {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+
+	switch {
+	case len(d) == 10 && !strings.HasPrefix(value, "+"):
+		return fmt.Sprintf("(%s) %s-%s", d[:3], d[3:6], d[6:]), nil
+	case len(d) == 11 && d[0] == '1':
+		return fmt.Sprintf("+1 (%s) %s-%s", d[1:4], d[4:7], d[7:]), nil
+	case len(d) >= 7 && len(d) <= 15:
+		return "+" + d, nil
+	}
+	return "", fmt.Errorf("%w: %q is not a phone number", ErrInvalidValue, value)
+}
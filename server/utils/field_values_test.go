@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInferFieldType tests name-based type inference for the expanded types
+func TestInferFieldType(t *testing.T) {
+	for field, want := range map[string]string{
+		"purchase_amount":        TypeCurrency,
+		"discount_rate":          TypePercent,
+		"investor_email_address": TypeEmail,
+		"company_phone":          TypePhone,
+		"premises_address":       TypeAddress,
+		"has_discount":           TypeBoolean,
+		"investor_signature":     TypeSignature,
+		"state_of_incorporation": TypeJurisdiction,
+		"company_entity_type":    TypeEntityType,
+		"purpose_description":    TypeMultiline,
+		"effective_date":         TypeDate,
+		"total_shares":           TypeNumber,
+		"company_name":           TypeText,
+		"agency_name":            TypeText, // "age" only matches a whole word
+		"stage_of_project":       TypeText,
+		"message_to_landlord":    TypeText,
+		"number_of_directors":    TypeNumber,
+		"invoice_number":         TypeText, // Identifiers such as "INV-2024-001"
+		"payment_number":         TypeText,
+		"phone_number":           TypePhone,
+		"interest_rate":          TypePercent,
+		"hourly_rate":            TypeText, // Could be "$150" or "150/hour"
+	} {
+		assert.Equal(t, want, InferFieldType(field), field)
+	}
+}
+
+// TestInferFieldTypeInContext tests that the text around a placeholder beats the name
+func TestInferFieldTypeInContext(t *testing.T) {
+	tests := []struct {
+		field, before, after, want, source string
+	}{
+		{"purchase_price", "for a total of ", " payable", TypeCurrency, SourceName},
+		{"investment", "in exchange for $", " (the", TypeCurrency, SourceContext},
+		{"discount", "a discount of ", "% off the price", TypePercent, SourceContext},
+		{"effective", "This Agreement is dated as of ", ", by", TypeDate, SourceContext},
+		{"notice", "outdated ", "", TypeText, SourceDefault},
+		{"has_deposit", "$", "", TypeBoolean, SourceName},
+	}
+	for _, tt := range tests {
+		got := InferFieldTypeInContext(tt.field, tt.before, tt.after)
+		assert.Equal(t, tt.want, got.Type, tt.field)
+		assert.Equal(t, tt.source, got.Source, tt.field)
+		assert.NotEmpty(t, got.Reason, tt.field)
+	}
+}
+
+// TestLoadTypeDictionary tests that custom keywords take precedence over built-in ones
+func TestLoadTypeDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"currency": ["retainer", "closing amount"], "date": ["closing"]}`), 0o644))
+	require.NoError(t, LoadTypeDictionary(path))
+	t.Cleanup(func() { LoadTypeDictionary("") })
+
+	assert.Equal(t, TypeCurrency, InferFieldType("monthly_retainer"))
+	assert.Equal(t, TypeDate, InferFieldType("closing"))
+	assert.Equal(t, TypeCurrency, InferFieldType("final_closing_amount"))
+	assert.Equal(t, SourceDictionary, ExplainFieldType("closing").Source)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"money": ["retainer"]}`), 0o644))
+	assert.Error(t, LoadTypeDictionary(path))
+}
+
+// TestNormalizeAndRenderValue tests validation, stored form and document rendering per type
+func TestNormalizeAndRenderValue(t *testing.T) {
+	tests := []struct {
+		fieldType, input, stored, rendered string
+		options                            []string
+	}{
+		{TypeCurrency, "$1,500,000", "1500000", "$1,500,000", nil},
+		{TypeCurrency, "2.5m", "2500000", "$2,500,000", nil},
+		{TypeCurrency, "1234.5", "1234.5", "$1,234.50", nil},
+		{TypePercent, "20%", "20", "20%", nil},
+		{TypeDate, "March 1, 2024", "2024-03-01", "March 1, 2024", nil},
+		{TypeEmail, "Jane Doe <Jane@Example.com>", "Jane@Example.com", "Jane@Example.com", nil},
+		{TypePhone, "555.123.4567", "(555) 123-4567", "(555) 123-4567", nil},
+		{TypeAddress, "1 Main St\r\n Springfield, IL", `{"street":"1 Main St","city":"Springfield","region":"IL"}`, "1 Main St, Springfield, IL", nil},
+		{TypeBoolean, "y", "Yes", "Yes", nil},
+		{TypeSelect, "quarterly", "Quarterly", "Quarterly", []string{"Monthly", "Quarterly"}},
+		{TypeJurisdiction, "the State of DE", "Delaware", "Delaware", nil},
+		{TypeJurisdiction, "England and Wales", "England and Wales", "England and Wales", nil},
+		{TypeEntityType, "LLC", "limited liability company", "limited liability company", nil},
+		{TypeSignature, "/s/ Jane  Doe", "Jane Doe", "/s/ Jane Doe", nil},
+		{TypeMultiline, "line one\r\nline two", "line one\nline two", "line one\nline two", nil},
+	}
+
+	for _, tt := range tests {
+		stored, err := NormalizeValue(tt.fieldType, tt.input, tt.options)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.stored, stored, tt.input)
+		assert.Equal(t, tt.rendered, RenderValue(tt.fieldType, stored, ""), tt.input)
+	}
+}
+
+// TestNormalizeValueRejects tests that values that don't fit their type are rejected
+func TestNormalizeValueRejects(t *testing.T) {
+	for fieldType, input := range map[string]string{
+		TypeCurrency:   "a lot",
+		TypePercent:    "twenty",
+		TypeDate:       "next Tuesday",
+		TypeEmail:      "jane@",
+		TypePhone:      "12",
+		TypeBoolean:    "maybe",
+		TypeEntityType: "cooperative",
+	} {
+		_, err := NormalizeValue(fieldType, input, nil)
+		assert.ErrorIs(t, err, ErrInvalidValue, fieldType)
+	}
+
+	_, err := NormalizeValue(TypeSelect, "Weekly", []string{"Monthly", "Quarterly"})
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
@@ -0,0 +1,474 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
+
+// Webhook events
+const (
+	EventSessionCreated   = "session.created"
+	EventSessionCompleted = "session.completed"
+	EventSessionGenerated = "session.generated"
+	EventTest             = "webhook.test" // Sent by the test-delivery endpoint only
+)
+
+// Events lists the events a webhook can subscribe to
+var Events = []string{EventSessionCreated, EventSessionCompleted, EventSessionGenerated}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	maxAttempts   = 5
+	maxDeliveries = 50 // Deliveries kept per webhook
+	// At most deliveryWorkers deliveries are sent at once; deliveryQueueSize more wait
+	deliveryWorkers   = 4
+	deliveryQueueSize = 100
+)
+
+// Dispatcher is a thread-safe in-memory registry of webhooks that delivers session
+// events to them on a bounded pool of workers, retrying failed deliveries with
+// exponential backoff
+type Dispatcher struct {
+	mu         sync.RWMutex
+	webhooks   map[string]*models.Webhook
+	deliveries map[string][]*models.WebhookDelivery // webhook ID -> deliveries, oldest first
+	pending    chan outgoing
+
+	client       *http.Client
+	backoff      time.Duration // Wait before the first retry; doubled for each one after
+	allowPrivate bool          // Whether webhooks may point at loopback, link-local or private addresses
+}
+
+// outgoing is a delivery waiting for a worker, with its payload
+type outgoing struct {
+	hook     models.Webhook
+	delivery *models.WebhookDelivery
+	body     []byte
+	attempts int // Attempts made so far
+}
+
+// NewDispatcher creates a dispatcher with no webhooks. Webhooks may only reach
+// public addresses unless WEBHOOK_ALLOW_PRIVATE is true.
+func NewDispatcher() *Dispatcher {
+	return newDispatcher(deliveryWorkers, deliveryQueueSize)
+}
+
+func newDispatcher(workers, size int) *Dispatcher {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE"))
+	d := &Dispatcher{
+		webhooks:     make(map[string]*models.Webhook),
+		deliveries:   make(map[string][]*models.WebhookDelivery),
+		pending:      make(chan outgoing, size),
+		backoff:      time.Second,
+		allowPrivate: allowPrivate,
+	}
+	// Addresses are checked when connecting too, so a host name that resolves to
+	// a private address, or a redirect to one, is refused as well
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: d.checkAddress}
+	d.client = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Create validates and saves a webhook. A signing secret is generated unless one
+// is given; the returned copy is the only one that includes it.
+func (d *Dispatcher) Create(w models.Webhook) (models.Webhook, error) {
+	w, err := d.normalize(w)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if w.ID, err = session.NewID(); err != nil {
+		return models.Webhook{}, err
+	}
+	if w.Secret == "" {
+		if w.Secret, err = session.NewID(); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+
+	d.mu.Lock()
+	d.webhooks[w.ID] = &w
+	d.mu.Unlock()
+
+	return w, nil
+}
+
+// Get returns a webhook without its secret
+func (d *Dispatcher) Get(id string) (models.Webhook, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	w, exists := d.webhooks[id]
+	if !exists {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	return redacted(*w), nil
+}
+
+// List returns every webhook without secrets, oldest first
+func (d *Dispatcher) List() []models.Webhook {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	list := make([]models.Webhook, 0, len(d.webhooks))
+	for _, w := range d.webhooks {
+		list = append(list, redacted(*w))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Update replaces a webhook's settings. The secret is kept unless a new one is given.
+func (d *Dispatcher) Update(id string, w models.Webhook) (models.Webhook, error) {
+	w, err := d.normalize(w)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	existing, exists := d.webhooks[id]
+	if !exists {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	if w.Secret == "" {
+		w.Secret = existing.Secret
+	}
+	w.ID, w.CreatedAt, w.UpdatedAt = id, existing.CreatedAt, time.Now()
+	*existing = w
+	return redacted(w), nil
+}
+
+// Delete removes a webhook and its delivery log
+func (d *Dispatcher) Delete(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+	delete(d.webhooks, id)
+	delete(d.deliveries, id)
+	return nil
+}
+
+// Deliveries returns a webhook's recent deliveries, newest first
+func (d *Dispatcher) Deliveries(id string) ([]models.WebhookDelivery, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, exists := d.webhooks[id]; !exists {
+		return nil, ErrWebhookNotFound
+	}
+	log := d.deliveries[id]
+	list := make([]models.WebhookDelivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		delivery := *log[i]
+		delivery.Attempts = slices.Clone(delivery.Attempts)
+		list = append(list, delivery)
+	}
+	return list, nil
+}
+
+// Test sends a webhook.test event once, without retries, and returns how it went
+func (d *Dispatcher) Test(id string) (models.WebhookDelivery, error) {
+	d.mu.RLock()
+	w, exists := d.webhooks[id]
+	var hook models.Webhook
+	if exists {
+		hook = *w
+	}
+	d.mu.RUnlock()
+	if !exists {
+		return models.WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	delivery, body, err := d.newDelivery(hook, EventTest, nil)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	d.finish(delivery, d.attempt(hook, delivery, body))
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return *delivery, nil
+}
+
+// SessionListener is a session.Listener that delivers the session's lifecycle
+// events to every webhook subscribed to them
+func (d *Dispatcher) SessionListener(s *models.Session, event models.SessionEvent) {
+	name := webhookEvent(event)
+	if name == "" {
+		return
+	}
+
+	d.mu.RLock()
+	var hooks []models.Webhook
+	for _, w := range d.webhooks {
+		if (len(w.Events) == 0 || slices.Contains(w.Events, name)) && matchesTemplate(*w, s) {
+			hooks = append(hooks, *w)
+		}
+	}
+	d.mu.RUnlock()
+
+	// The listener runs with the session locked, so the snapshot is consistent.
+	progress, total := session.Completion(s)
+	snapshot := &models.WebhookSession{
+		SessionID:    s.ID,
+		Template:     s.Template,
+		DocumentType: s.DocumentType,
+		Answers:      make(map[string]string, len(s.Answers)),
+		Progress:     progress,
+		Total:        total,
+		IsCompleted:  progress == total,
+	}
+	for field, answer := range s.Answers {
+		snapshot.Answers[field] = answer
+	}
+
+	for _, hook := range hooks {
+		delivery, body, err := d.newDelivery(hook, name, snapshot)
+		if err != nil {
+			continue
+		}
+		// Never block the session: a delivery that finds the queue full fails at once
+		select {
+		case d.pending <- outgoing{hook: hook, delivery: delivery, body: body}:
+		default:
+			d.mu.Lock()
+			delivery.Attempts = append(delivery.Attempts, models.DeliveryAttempt{At: time.Now(), Error: "delivery queue is full"})
+			d.mu.Unlock()
+			d.finish(delivery, false)
+		}
+	}
+}
+
+// work sends queued deliveries, one at a time
+func (d *Dispatcher) work() {
+	for next := range d.pending {
+		d.deliver(next)
+	}
+}
+
+// webhookEvent names the webhook event for a session event, or "" when it has none
+func webhookEvent(event models.SessionEvent) string {
+	switch {
+	case event.Type == session.EventCreated:
+		return EventSessionCreated
+	case event.Type == session.EventCompleted:
+		return EventSessionCompleted
+	case event.Type == session.EventGeneration && event.Status == session.GenerationCompleted:
+		return EventSessionGenerated
+	}
+	return ""
+}
+
+// matchesTemplate reports whether a webhook applies to a session's template
+func matchesTemplate(w models.Webhook, s *models.Session) bool {
+	return w.Template == "" || strings.EqualFold(w.Template, s.Template) || strings.EqualFold(w.Template, s.DocumentType)
+}
+
+// newDelivery logs a pending delivery and builds its payload
+func (d *Dispatcher) newDelivery(hook models.Webhook, event string, snapshot *models.WebhookSession) (*models.WebhookDelivery, []byte, error) {
+	id, err := session.NewID()
+	if err != nil {
+		return nil, nil, err
+	}
+	delivery := &models.WebhookDelivery{
+		ID:        id,
+		WebhookID: hook.ID,
+		Event:     event,
+		Status:    StatusPending,
+		Attempts:  []models.DeliveryAttempt{},
+		CreatedAt: time.Now(),
+	}
+	if snapshot != nil {
+		delivery.SessionID = snapshot.SessionID
+	}
+
+	body, err := json.Marshal(models.WebhookPayload{ID: id, Event: event, CreatedAt: delivery.CreatedAt, Session: snapshot})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d.mu.Lock()
+	log := append(d.deliveries[hook.ID], delivery)
+	if len(log) > maxDeliveries {
+		log = log[len(log)-maxDeliveries:]
+	}
+	d.deliveries[hook.ID] = log
+	d.mu.Unlock()
+
+	return delivery, body, nil
+}
+
+// deliver makes a delivery's next attempt. A failed attempt goes back on the queue
+// after a wait that doubles each time, so waiting retries don't hold a worker,
+// until the delivery runs out of attempts.
+func (d *Dispatcher) deliver(next outgoing) {
+	if d.attempt(next.hook, next.delivery, next.body) {
+		d.finish(next.delivery, true)
+		return
+	}
+	next.attempts++
+	if next.attempts >= maxAttempts {
+		d.finish(next.delivery, false)
+		return
+	}
+	wait := d.backoff << (next.attempts - 1)
+	time.AfterFunc(wait, func() { d.pending <- next })
+}
+
+// attempt posts a delivery once and logs the result; true on a 2xx response
+func (d *Dispatcher) attempt(hook models.Webhook, delivery *models.WebhookDelivery, body []byte) bool {
+	start := time.Now()
+	result := models.DeliveryAttempt{At: start}
+
+	resp, err := d.post(hook, delivery, body)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		resp.Body.Close()
+		result.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	d.mu.Lock()
+	delivery.Attempts = append(delivery.Attempts, result)
+	d.mu.Unlock()
+	return result.Error == ""
+}
+
+// post sends a signed delivery
+func (d *Dispatcher) post(hook models.Webhook, delivery *models.WebhookDelivery, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", hook.ID)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(hook.Secret, timestamp, body))
+	return d.client.Do(req)
+}
+
+// finish records a delivery's final status
+func (d *Dispatcher) finish(delivery *models.WebhookDelivery, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ok {
+		delivery.Status = StatusSucceeded
+	} else {
+		delivery.Status = StatusFailed
+	}
+}
+
+// Sign is the hex HMAC-SHA256 of "<timestamp>.<body>" with the webhook's secret,
+// sent as X-Webhook-Signature: sha256=<signature>
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalize checks a webhook's URL and events
+func (d *Dispatcher) normalize(w models.Webhook) (models.Webhook, error) {
+	w.URL = strings.TrimSpace(w.URL)
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.Webhook{}, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if !d.allowPrivate && isPrivateHost(u.Hostname()) {
+		return models.Webhook{}, fmt.Errorf("%w: url must not point at a loopback, link-local or private address", ErrInvalidWebhook)
+	}
+
+	events := []string{}
+	for _, event := range w.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(Events, event) {
+			return models.Webhook{}, fmt.Errorf("%w: unknown event %q (expected one of: %s)", ErrInvalidWebhook, event, strings.Join(Events, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	w.Events = events
+	w.Template = strings.TrimSpace(w.Template)
+	return w, nil
+}
+
+// checkAddress refuses connections to private addresses; it runs after the host
+// name is resolved
+func (d *Dispatcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !d.allowPrivate && isPrivateHost(host) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// isPrivateHost reports whether a host is localhost or a loopback, link-local,
+// private or unspecified IP address. Other host names are checked once resolved.
+func isPrivateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified())
+}
+
+// redacted hides a webhook's secret
+func redacted(w models.Webhook) models.Webhook {
+	w.Secret = ""
+	w.Events = slices.Clone(w.Events)
+	return w
+}
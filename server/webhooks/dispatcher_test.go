@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you/lexsy-mvp/server/models"
+	"github.com/you/lexsy-mvp/server/session"
+)
+
+// receiver records the requests a webhook endpoint gets, failing the first few
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// TestDeliverySignedAndRetried tests that deliveries are signed and retried until the receiver accepts them
+func TestDeliverySignedAndRetried(t *testing.T) {
+	rec := &receiver{failures: 2}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	d := NewDispatcher()
+	d.backoff = time.Millisecond
+	d.allowPrivate = true // The receiver listens on localhost
+	hook, err := d.Create(models.Webhook{URL: server.URL, Events: []string{"session.completed"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, hook.Secret)
+
+	// Progress counts required fields only, so the optional note doesn't hold it back
+	s := &models.Session{ID: "s1", Template: "NDA", Fields: []string{"name", "note"}, Answers: map[string]string{"name": "Ada"}, Optional: map[string]bool{"note": true}}
+	d.SessionListener(s, models.SessionEvent{Type: session.EventCreated})
+	d.SessionListener(s, models.SessionEvent{Type: session.EventCompleted})
+
+	require.Eventually(t, func() bool {
+		deliveries, _ := d.Deliveries(hook.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusSucceeded
+	}, 2*time.Second, 5*time.Millisecond)
+
+	deliveries, err := d.Deliveries(hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "session.completed", deliveries[0].Event)
+	assert.Equal(t, "s1", deliveries[0].SessionID)
+	require.Len(t, deliveries[0].Attempts, 3)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].Attempts[0].StatusCode)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].Attempts[2].StatusCode)
+	assert.Empty(t, deliveries[0].Attempts[2].Error)
+
+	// Every attempt is signed with the webhook's secret
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	req, body := rec.requests[2], rec.bodies[2]
+	assert.Equal(t, "session.completed", req.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, deliveries[0].ID, req.Header.Get("X-Webhook-Delivery"))
+	assert.Equal(t, "sha256="+Sign(hook.Secret, req.Header.Get("X-Webhook-Timestamp"), body), req.Header.Get("X-Webhook-Signature"))
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.NotNil(t, payload.Session)
+	assert.Equal(t, "Ada", payload.Session.Answers["name"])
+	assert.True(t, payload.Session.IsCompleted)
+	assert.Equal(t, 1, payload.Session.Progress)
+	assert.Equal(t, 1, payload.Session.Total)
+
+	// The secret is only shown on creation
+	got, err := d.Get(hook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Secret)
+}
+
+// TestDeliveryGivesUp tests that a delivery fails after the last attempt
+func TestDeliveryGivesUp(t *testing.T) {
+	rec := &receiver{failures: maxAttempts}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	d := NewDispatcher()
+	d.backoff = time.Millisecond
+	d.allowPrivate = true // The receiver listens on localhost
+	hook, err := d.Create(models.Webhook{URL: server.URL})
+	require.NoError(t, err)
+
+	d.SessionListener(&models.Session{ID: "s1"}, models.SessionEvent{Type: session.EventCreated})
+
+	require.Eventually(t, func() bool {
+		deliveries, _ := d.Deliveries(hook.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusFailed
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, maxAttempts, rec.count())
+}
+
+// TestWebhookTemplateFilter tests that a template webhook only gets that template's sessions
+func TestWebhookTemplateFilter(t *testing.T) {
+	rec := &receiver{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	d := NewDispatcher()
+	d.allowPrivate = true
+	nda, err := d.Create(models.Webhook{URL: server.URL, Template: "nda"})
+	require.NoError(t, err)
+	global, err := d.Create(models.Webhook{URL: server.URL})
+	require.NoError(t, err)
+
+	d.SessionListener(&models.Session{ID: "s1", Template: "NDA"}, models.SessionEvent{Type: session.EventCreated})
+	d.SessionListener(&models.Session{ID: "s2", Template: "SAFE"}, models.SessionEvent{Type: session.EventCreated})
+	// Generation only counts once it completes; answers are not webhook events
+	d.SessionListener(&models.Session{ID: "s2"}, models.SessionEvent{Type: session.EventGeneration, Status: session.GenerationStarted})
+	d.SessionListener(&models.Session{ID: "s2"}, models.SessionEvent{Type: session.EventAnswer})
+
+	require.Eventually(t, func() bool { return rec.count() == 3 }, 2*time.Second, 5*time.Millisecond)
+
+	deliveries, err := d.Deliveries(nda.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "s1", deliveries[0].SessionID)
+
+	deliveries, err = d.Deliveries(global.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+}
+
+// TestWebhookValidation tests checking webhook URLs and events on create and update
+func TestWebhookValidation(t *testing.T) {
+	d := NewDispatcher()
+
+	_, err := d.Create(models.Webhook{URL: "ftp://example.com/hook"})
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = d.Create(models.Webhook{URL: "https://example.com/hook", Events: []string{"session.deleted"}})
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	for _, url := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest", "http://10.0.0.5/hook", "http://192.168.1.1/hook", "http://0.0.0.0/hook"} {
+		_, err = d.Create(models.Webhook{URL: url})
+		assert.ErrorIs(t, err, ErrInvalidWebhook, url)
+	}
+
+	hook, err := d.Create(models.Webhook{URL: " https://example.com/hook ", Events: []string{"Session.Created", "session.created"}, Secret: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", hook.URL)
+	assert.Equal(t, []string{"session.created"}, hook.Events)
+	assert.Equal(t, "s3cret", hook.Secret)
+
+	// Updating without a secret keeps the old one
+	_, err = d.Update(hook.ID, models.Webhook{URL: "https://example.com/other"})
+	require.NoError(t, err)
+	d.mu.RLock()
+	assert.Equal(t, "s3cret", d.webhooks[hook.ID].Secret)
+	d.mu.RUnlock()
+
+	require.NoError(t, d.Delete(hook.ID))
+	_, err = d.Deliveries(hook.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+// TestPrivateAddressRefusedOnConnect tests that deliveries to a private address
+// fail even when the webhook was saved while private addresses were allowed
+func TestPrivateAddressRefusedOnConnect(t *testing.T) {
+	rec := &receiver{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	d := NewDispatcher()
+	d.allowPrivate = true
+	hook, err := d.Create(models.Webhook{URL: server.URL})
+	require.NoError(t, err)
+
+	d.allowPrivate = false
+	delivery, err := d.Test(hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, delivery.Status)
+	require.Len(t, delivery.Attempts, 1)
+	assert.Contains(t, delivery.Attempts[0].Error, "is not public")
+	assert.Zero(t, rec.count())
+}
+
+// TestDeliveryQueueFull tests that a delivery that finds every worker busy and the
+// queue full fails instead of blocking the session
+func TestDeliveryQueueFull(t *testing.T) {
+	d := newDispatcher(0, 1)
+	d.allowPrivate = true
+	hook, err := d.Create(models.Webhook{URL: "http://127.0.0.1/hook"})
+	require.NoError(t, err)
+
+	d.SessionListener(&models.Session{ID: "s1"}, models.SessionEvent{Type: session.EventCreated})
+	d.SessionListener(&models.Session{ID: "s2"}, models.SessionEvent{Type: session.EventCreated})
+
+	deliveries, err := d.Deliveries(hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "s2", deliveries[0].SessionID)
+	assert.Equal(t, StatusFailed, deliveries[0].Status)
+	require.Len(t, deliveries[0].Attempts, 1)
+	assert.Equal(t, "delivery queue is full", deliveries[0].Attempts[0].Error)
+	assert.Equal(t, StatusPending, deliveries[1].Status)
+}
+
+// TestRetryWaitHoldsNoWorker tests that a failing webhook waiting to retry doesn't
+// stop the only worker from delivering to a healthy one
+func TestRetryWaitHoldsNoWorker(t *testing.T) {
+	dead := &receiver{failures: maxAttempts}
+	deadServer := httptest.NewServer(dead)
+	defer deadServer.Close()
+	healthy := &receiver{}
+	healthyServer := httptest.NewServer(healthy)
+	defer healthyServer.Close()
+
+	d := newDispatcher(1, 1)
+	d.backoff = time.Hour
+	d.allowPrivate = true
+	deadHook, err := d.Create(models.Webhook{URL: deadServer.URL, Template: "a"})
+	require.NoError(t, err)
+	healthyHook, err := d.Create(models.Webhook{URL: healthyServer.URL, Template: "b"})
+	require.NoError(t, err)
+
+	d.SessionListener(&models.Session{ID: "s1", Template: "a"}, models.SessionEvent{Type: session.EventCreated})
+	require.Eventually(t, func() bool { return dead.count() == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	d.SessionListener(&models.Session{ID: "s2", Template: "b"}, models.SessionEvent{Type: session.EventCreated})
+	require.Eventually(t, func() bool {
+		deliveries, _ := d.Deliveries(healthyHook.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusSucceeded
+	}, 2*time.Second, 5*time.Millisecond)
+
+	deliveries, err := d.Deliveries(deadHook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, deliveries[0].Status)
+}